@@ -0,0 +1,135 @@
+// Command solarman-gateway exposes the slaves behind a Solarman data logging
+// stick as a standard Modbus TCP server, or as a server for raw Modbus RTU
+// frames over TCP with -mode rtu.
+//
+// Usage:
+//
+//	solarman-gateway -logger 192.168.1.10:8899 -serial 1234567891 [-listen :502] [-mode tcp|rtu] [-slave 1] [-units 1=1,2=3]
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"log/slog"
+	"math"
+	"net"
+	"os"
+	"os/signal"
+	"strconv"
+	"strings"
+	"syscall"
+
+	"github.com/tlmnb/gosolarman"
+)
+
+func main() {
+	listen := flag.String("listen", ":502", "address to accept clients on")
+	mode := flag.String("mode", "tcp", "protocol of the clients: tcp for Modbus TCP, rtu for Modbus RTU over TCP")
+	logger := flag.String("logger", "", "address of the data logging stick (e.g., 192.168.1.10:8899 or udp://192.168.1.10:8899)")
+	serial := flag.Uint("serial", 0, "serial number of the data logging stick")
+	slave := flag.Uint("slave", 1, "slave ID for requests to unit ID 0 or 255")
+	units := flag.String("units", "", "comma-separated unit=slave ID mappings; other unit IDs are rejected if set")
+	timeout := flag.Duration("timeout", gosolarman.Timeout, "timeout of a request to the data logging stick")
+	verbose := flag.Bool("v", false, "log every frame")
+	flag.Parse()
+
+	if *logger == "" {
+		fmt.Fprintln(os.Stderr, "solarman-gateway: -logger is required")
+		flag.Usage()
+		os.Exit(2)
+	}
+	if *mode != "tcp" && *mode != "rtu" {
+		fmt.Fprintf(os.Stderr, "solarman-gateway: invalid -mode %q\n", *mode)
+		os.Exit(2)
+	}
+	if *serial > math.MaxUint32 {
+		fmt.Fprintf(os.Stderr, "solarman-gateway: invalid -serial %d\n", *serial)
+		os.Exit(2)
+	}
+	if *slave > math.MaxUint8 {
+		fmt.Fprintf(os.Stderr, "solarman-gateway: invalid -slave %d\n", *slave)
+		os.Exit(2)
+	}
+	unitIDs, err := parseUnitIDs(*units)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "solarman-gateway: -units: %v\n", err)
+		os.Exit(2)
+	}
+
+	level := slog.LevelInfo
+	if *verbose {
+		level = slog.LevelDebug
+	}
+	log := slog.New(slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{Level: level}))
+
+	handler := gosolarman.NewSolarmanClientHandler(*logger, uint32(*serial))
+	handler.SlaveID = byte(*slave)
+	handler.Timeout = *timeout
+	handler.StructuredLogger = log
+	defer handler.Close()
+
+	var gateway interface {
+		Serve(listener net.Listener) error
+		Close() error
+	}
+	if *mode == "rtu" {
+		rtuGateway := gosolarman.NewRTUOverTCPGateway(handler)
+		rtuGateway.UnitIDs = unitIDs
+		gateway = rtuGateway
+	} else {
+		tcpGateway := gosolarman.NewModbusTCPGateway(handler)
+		tcpGateway.UnitIDs = unitIDs
+		gateway = tcpGateway
+	}
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	go func() {
+		<-ctx.Done()
+		gateway.Close()
+	}()
+
+	listener, err := net.Listen("tcp", *listen)
+	if err != nil {
+		log.Error("listen failed", slog.Any("error", err))
+		os.Exit(1)
+	}
+	log.Info("gateway started", slog.String("listen", listener.Addr().String()), slog.String("mode", *mode), slog.String("logger", *logger))
+	if err := gateway.Serve(listener); err != nil && !errors.Is(err, net.ErrClosed) {
+		log.Error("serve failed", slog.Any("error", err))
+		os.Exit(1)
+	}
+	log.Info("gateway stopped")
+}
+
+// parseUnitIDs parses comma-separated unit=slave ID mappings.
+//
+// Parameters:
+//   - s: The mappings (e.g., "1=1,2=3"), or an empty string for none.
+//
+// Returns:
+//   - The slave IDs by unit ID, or nil if s is empty.
+//   - An error if a mapping is invalid.
+func parseUnitIDs(s string) (map[byte]byte, error) {
+	if s == "" {
+		return nil, nil
+	}
+	unitIDs := make(map[byte]byte)
+	for _, mapping := range strings.Split(s, ",") {
+		unit, slave, ok := strings.Cut(mapping, "=")
+		if !ok {
+			return nil, fmt.Errorf("invalid mapping %q", mapping)
+		}
+		unitID, err := strconv.ParseUint(strings.TrimSpace(unit), 0, 8)
+		if err != nil {
+			return nil, fmt.Errorf("invalid unit ID in %q: %w", mapping, err)
+		}
+		slaveID, err := strconv.ParseUint(strings.TrimSpace(slave), 0, 8)
+		if err != nil {
+			return nil, fmt.Errorf("invalid slave ID in %q: %w", mapping, err)
+		}
+		unitIDs[byte(unitID)] = byte(slaveID)
+	}
+	return unitIDs, nil
+}
@@ -0,0 +1,113 @@
+// Command solarman-pcap prints the Solarman V5 frames of pcap and pcapng
+// captures, one annotated frame per line.
+//
+// Usage:
+//
+//	solarman-pcap [-ports 8899,10000] [-x] capture.pcapng...
+//
+// Use - to read a capture from standard input, e.g.
+//
+//	tcpdump -i eth0 -w - port 8899 | solarman-pcap -
+package main
+
+import (
+	"encoding/hex"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/tlmnb/gosolarman/pcapsolarman"
+)
+
+func main() {
+	ports := flag.String("ports", "8899,10000", "comma-separated TCP ports of Solarman V5 traffic")
+	raw := flag.Bool("x", false, "print the raw frames in hex")
+	flag.Parse()
+
+	if flag.NArg() == 0 {
+		fmt.Fprintln(os.Stderr, "solarman-pcap: no capture given")
+		flag.Usage()
+		os.Exit(2)
+	}
+	portList, err := parsePorts(*ports)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "solarman-pcap: -ports: %v\n", err)
+		os.Exit(2)
+	}
+
+	failed := false
+	for _, path := range flag.Args() {
+		if err := printCapture(os.Stdout, path, portList, *raw); err != nil {
+			fmt.Fprintf(os.Stderr, "solarman-pcap: %s: %v\n", path, err)
+			failed = true
+		}
+	}
+	if failed {
+		os.Exit(1)
+	}
+}
+
+// printCapture prints the annotated frames of a capture.
+//
+// Parameters:
+//   - w: The writer to print to.
+//   - path: The path of the capture, or - for standard input.
+//   - ports: The TCP ports of Solarman V5 traffic.
+//   - raw: Whether to print the raw frames in hex.
+//
+// Returns:
+//   - An error if the capture cannot be read or is malformed.
+func printCapture(w io.Writer, path string, ports []uint16, raw bool) error {
+	var r io.Reader = os.Stdin
+	if path != "-" {
+		file, err := os.Open(path)
+		if err != nil {
+			return err
+		}
+		defer file.Close()
+		r = file
+	}
+
+	reader, err := pcapsolarman.NewReader(r)
+	if err != nil {
+		return err
+	}
+	reader.Ports = ports
+	for {
+		event, err := reader.Next()
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		fmt.Fprintln(w, event)
+		if raw {
+			fmt.Fprintf(w, "\t%s\n", hex.EncodeToString(event.Raw))
+		}
+	}
+}
+
+// parsePorts parses comma-separated TCP ports.
+//
+// Parameters:
+//   - s: The ports (e.g., "8899,10000").
+//
+// Returns:
+//   - The ports.
+//   - An error if a port is invalid.
+func parsePorts(s string) ([]uint16, error) {
+	var ports []uint16
+	for _, field := range strings.Split(s, ",") {
+		port, err := strconv.ParseUint(strings.TrimSpace(field), 10, 16)
+		if err != nil {
+			return nil, fmt.Errorf("invalid port %q: %w", field, err)
+		}
+		ports = append(ports, uint16(port))
+	}
+	return ports, nil
+}
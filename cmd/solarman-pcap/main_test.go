@@ -0,0 +1,122 @@
+package main
+
+import (
+	"encoding/binary"
+	"net/netip"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/tlmnb/gosolarman"
+)
+
+// captureFile writes a pcap file with one Ethernet packet per payload, sent
+// from source to destination, and returns its path.
+func captureFile(t *testing.T, source, destination netip.AddrPort, payloads ...[]byte) string {
+	t.Helper()
+	file := binary.LittleEndian.AppendUint32(nil, 0xA1B2C3D4)
+	file = binary.LittleEndian.AppendUint16(file, 2)
+	file = binary.LittleEndian.AppendUint16(file, 4)
+	file = append(file, make([]byte, 8)...)
+	file = binary.LittleEndian.AppendUint32(file, 65535)
+	file = binary.LittleEndian.AppendUint32(file, 1) // Ethernet
+
+	sequence := uint32(1000)
+	captured := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+	for _, payload := range payloads {
+		tcp := make([]byte, 20, 20+len(payload))
+		binary.BigEndian.PutUint16(tcp[0:2], source.Port())
+		binary.BigEndian.PutUint16(tcp[2:4], destination.Port())
+		binary.BigEndian.PutUint32(tcp[4:8], sequence)
+		tcp[12] = 5 << 4
+		tcp[13] = 0x18 // PSH, ACK
+		tcp = append(tcp, payload...)
+		sequence += uint32(len(payload))
+
+		ip := make([]byte, 20, 20+len(tcp))
+		ip[0] = 0x45
+		binary.BigEndian.PutUint16(ip[2:4], uint16(20+len(tcp)))
+		ip[8] = 64
+		ip[9] = 6 // TCP
+		copy(ip[12:16], source.Addr().AsSlice())
+		copy(ip[16:20], destination.Addr().AsSlice())
+		ip = append(ip, tcp...)
+
+		packet := make([]byte, 14, 14+len(ip))
+		binary.BigEndian.PutUint16(packet[12:14], 0x0800) // IPv4
+		packet = append(packet, ip...)
+
+		file = binary.LittleEndian.AppendUint32(file, uint32(captured.Unix()))
+		file = binary.LittleEndian.AppendUint32(file, uint32(captured.Nanosecond()/1000))
+		file = binary.LittleEndian.AppendUint32(file, uint32(len(packet)))
+		file = binary.LittleEndian.AppendUint32(file, uint32(len(packet)))
+		file = append(file, packet...)
+		captured = captured.Add(time.Millisecond)
+	}
+
+	path := filepath.Join(t.TempDir(), "capture.pcap")
+	if err := os.WriteFile(path, file, 0o644); err != nil {
+		t.Fatalf("WriteFile failed: %v", err)
+	}
+	return path
+}
+
+func TestParsePorts(t *testing.T) {
+	ports, err := parsePorts("8899, 10000")
+	if err != nil {
+		t.Fatalf("parsePorts failed: %v", err)
+	}
+	if !slices.Equal(ports, []uint16{8899, 10000}) {
+		t.Errorf("Expected [8899 10000], got %v", ports)
+	}
+
+	for _, invalid := range []string{"", "8899,", "http", "65536", "-1"} {
+		if _, err := parsePorts(invalid); err == nil {
+			t.Errorf("Expected an error for %q", invalid)
+		}
+	}
+}
+
+func TestPrintCapture(t *testing.T) {
+	heartbeat, err := (&gosolarman.Frame{
+		Header:  &gosolarman.Header{ControlCode: gosolarman.ControlCodeHeartbeat, SequenceNumber: 0x0A00, LoggerSerialNumber: 1234567891},
+		Payload: &gosolarman.HeartbeatPayload{Data: []byte{0x00}},
+	}).Marshal()
+	if err != nil {
+		t.Fatalf("Marshal failed: %v", err)
+	}
+	stick := netip.MustParseAddrPort("192.168.1.10:8899")
+	client := netip.MustParseAddrPort("192.168.1.5:51234")
+	path := captureFile(t, stick, client, heartbeat, heartbeat)
+
+	var out strings.Builder
+	if err := printCapture(&out, path, []uint16{8899}, true); err != nil {
+		t.Fatalf("printCapture failed: %v", err)
+	}
+	lines := strings.Split(strings.TrimSuffix(out.String(), "\n"), "\n")
+	if len(lines) != 4 {
+		t.Fatalf("Expected 2 frames with their raw hex, got %q", out.String())
+	}
+	if expected := "192.168.1.10:8899 -> 192.168.1.5:51234 heartbeat seq=0x0A00 serial=1234567891 data=00"; !strings.HasSuffix(lines[0], expected) {
+		t.Errorf("Expected a line ending in %q, got %q", expected, lines[0])
+	}
+	if expected := "\ta5"; !strings.HasPrefix(lines[1], expected) {
+		t.Errorf("Expected the raw frame, got %q", lines[1])
+	}
+
+	// Frames on other ports are skipped.
+	out.Reset()
+	if err := printCapture(&out, path, []uint16{10000}, false); err != nil {
+		t.Fatalf("printCapture failed: %v", err)
+	}
+	if out.Len() != 0 {
+		t.Errorf("Expected no output, got %q", out.String())
+	}
+
+	if err := printCapture(&out, filepath.Join(t.TempDir(), "missing.pcap"), []uint16{8899}, false); !os.IsNotExist(err) {
+		t.Errorf("Expected a missing file error, got %v", err)
+	}
+}
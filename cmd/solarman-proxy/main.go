@@ -0,0 +1,74 @@
+// Command solarman-proxy lets many Solarman V5 clients share the single TCP
+// connection that a data logging stick allows.
+//
+// Usage:
+//
+//	solarman-proxy -logger 192.168.1.10:8899 -serial 1234567891 [-listen :8899]
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"log/slog"
+	"math"
+	"net"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/tlmnb/gosolarman"
+)
+
+func main() {
+	listen := flag.String("listen", ":8899", "address to accept clients on")
+	logger := flag.String("logger", "", "address of the data logging stick (e.g., 192.168.1.10:8899)")
+	serial := flag.Uint("serial", 0, "serial number of the data logging stick")
+	timeout := flag.Duration("timeout", gosolarman.Timeout, "timeout of a request to the data logging stick")
+	verbose := flag.Bool("v", false, "log every frame")
+	flag.Parse()
+
+	if *logger == "" {
+		fmt.Fprintln(os.Stderr, "solarman-proxy: -logger is required")
+		flag.Usage()
+		os.Exit(2)
+	}
+	if *serial > math.MaxUint32 {
+		fmt.Fprintf(os.Stderr, "solarman-proxy: invalid -serial %d\n", *serial)
+		os.Exit(2)
+	}
+
+	level := slog.LevelInfo
+	if *verbose {
+		level = slog.LevelDebug
+	}
+	log := slog.New(slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{Level: level}))
+
+	handler := gosolarman.NewSolarmanClientHandler(*logger, uint32(*serial))
+	handler.Timeout = *timeout
+	handler.StructuredLogger = log
+	defer handler.Close()
+
+	proxy := gosolarman.NewProxy(handler)
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	go func() {
+		<-ctx.Done()
+		proxy.Close()
+	}()
+
+	listener, err := net.Listen("tcp", *listen)
+	if err != nil {
+		log.Error("listen failed", slog.Any("error", err))
+		os.Exit(1)
+	}
+	log.Info("proxy started", slog.String("listen", listener.Addr().String()), slog.String("logger", *logger))
+	start := time.Now()
+	if err := proxy.Serve(listener); err != nil && !errors.Is(err, net.ErrClosed) {
+		log.Error("serve failed", slog.Any("error", err))
+		os.Exit(1)
+	}
+	log.Info("proxy stopped", slog.Duration("uptime", time.Since(start)))
+}
@@ -0,0 +1,184 @@
+// Command solarman-sim simulates a Solarman data logging stick for local
+// testing without hardware.
+//
+// Without -config, it simulates slave 1 with holding and input registers
+// 0-99 set to zero. A configuration file defines the register banks of each
+// slave by start address:
+//
+//	[
+//	  {"slave": 1, "holding": {"0x0000": [1, 2, 3]}, "input": {"100": [230, 50]}}
+//	]
+//
+// With -faults, the first answered requests misbehave in the given order,
+// e.g. -faults drop,delay=2s,duplicate,checksum,crc,sequence,unsolicited,close.
+//
+// Usage:
+//
+//	solarman-sim -serial 1234567891 [-listen :8899] [-config slaves.json] [-faults drop,delay=2s]
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"log/slog"
+	"math"
+	"net"
+	"os"
+	"os/signal"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/tlmnb/gosolarman/simsolarman"
+)
+
+// slaveConfig is the configuration of one simulated slave.
+type slaveConfig struct {
+	Slave   byte                `json:"slave"`   // Modbus slave ID.
+	Holding map[string][]uint16 `json:"holding"` // Holding registers by start address.
+	Input   map[string][]uint16 `json:"input"`   // Input registers by start address.
+}
+
+func main() {
+	listen := flag.String("listen", ":8899", "address to accept clients on")
+	serial := flag.Uint("serial", 0, "serial number of the simulated data logging stick")
+	config := flag.String("config", "", "JSON file with the register banks of the slaves")
+	faults := flag.String("faults", "", "comma-separated faults for the first answered requests (e.g., drop,delay=2s,close)")
+	verbose := flag.Bool("v", false, "log client connections and injected faults")
+	flag.Parse()
+
+	if *serial > math.MaxUint32 {
+		fmt.Fprintf(os.Stderr, "solarman-sim: invalid -serial %d\n", *serial)
+		os.Exit(2)
+	}
+
+	level := slog.LevelInfo
+	if *verbose {
+		level = slog.LevelDebug
+	}
+	log := slog.New(slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{Level: level}))
+
+	simulator := simsolarman.NewSimulator(uint32(*serial))
+	simulator.StructuredLogger = log
+	if *config == "" {
+		slave := simulator.Slave(0x01)
+		slave.SetHoldingRegisters(0, make([]uint16, 100)...)
+		slave.SetInputRegisters(0, make([]uint16, 100)...)
+	} else if err := loadConfig(simulator, *config); err != nil {
+		fmt.Fprintf(os.Stderr, "solarman-sim: -config: %v\n", err)
+		os.Exit(2)
+	}
+	script, err := parseFaults(*faults)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "solarman-sim: -faults: %v\n", err)
+		os.Exit(2)
+	}
+	simulator.InjectFaults(script...)
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	go func() {
+		<-ctx.Done()
+		simulator.Close()
+	}()
+
+	listener, err := net.Listen("tcp", *listen)
+	if err != nil {
+		log.Error("listen failed", slog.Any("error", err))
+		os.Exit(1)
+	}
+	log.Info("simulator started", slog.String("listen", listener.Addr().String()), slog.Uint64("serial", uint64(*serial)))
+	if err := simulator.Serve(listener); err != nil && !errors.Is(err, net.ErrClosed) {
+		log.Error("serve failed", slog.Any("error", err))
+		os.Exit(1)
+	}
+	log.Info("simulator stopped")
+}
+
+// loadConfig defines the slaves of a simulator from a configuration file.
+//
+// Parameters:
+//   - simulator: The simulator to configure.
+//   - path: The path of the JSON configuration file.
+//
+// Returns:
+//   - An error if the file cannot be read or is invalid.
+func loadConfig(simulator *simsolarman.Simulator, path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	var slaves []slaveConfig
+	if err := json.Unmarshal(data, &slaves); err != nil {
+		return err
+	}
+	for _, config := range slaves {
+		slave := simulator.Slave(config.Slave)
+		for start, values := range config.Holding {
+			address, err := strconv.ParseUint(start, 0, 16)
+			if err != nil {
+				return fmt.Errorf("slave %d: invalid holding register address %q: %w", config.Slave, start, err)
+			}
+			slave.SetHoldingRegisters(uint16(address), values...)
+		}
+		for start, values := range config.Input {
+			address, err := strconv.ParseUint(start, 0, 16)
+			if err != nil {
+				return fmt.Errorf("slave %d: invalid input register address %q: %w", config.Slave, start, err)
+			}
+			slave.SetInputRegisters(uint16(address), values...)
+		}
+	}
+	return nil
+}
+
+// faultKinds are the fault kinds by name.
+var faultKinds = map[string]simsolarman.FaultKind{
+	"none":        simsolarman.FaultNone,
+	"drop":        simsolarman.FaultDrop,
+	"delay":       simsolarman.FaultDelay,
+	"duplicate":   simsolarman.FaultDuplicate,
+	"checksum":    simsolarman.FaultChecksum,
+	"crc":         simsolarman.FaultCRC,
+	"sequence":    simsolarman.FaultSequence,
+	"unsolicited": simsolarman.FaultUnsolicited,
+	"close":       simsolarman.FaultClose,
+}
+
+// parseFaults parses a comma-separated fault script.
+//
+// Parameters:
+//   - s: The faults (e.g., "drop,delay=2s,close"), or an empty string for none.
+//
+// Returns:
+//   - The faults in order.
+//   - An error if a fault is invalid.
+func parseFaults(s string) ([]simsolarman.Fault, error) {
+	if s == "" {
+		return nil, nil
+	}
+	var faults []simsolarman.Fault
+	for _, field := range strings.Split(s, ",") {
+		name, delay, hasDelay := strings.Cut(strings.TrimSpace(field), "=")
+		kind, ok := faultKinds[name]
+		if !ok {
+			return nil, fmt.Errorf("unknown fault %q", name)
+		}
+		fault := simsolarman.Fault{Kind: kind}
+		if hasDelay != (kind == simsolarman.FaultDelay) {
+			return nil, fmt.Errorf("invalid fault %q", field)
+		}
+		if hasDelay {
+			var err error
+			if fault.Delay, err = time.ParseDuration(delay); err != nil {
+				return nil, fmt.Errorf("invalid delay in %q: %w", field, err)
+			}
+		}
+		faults = append(faults, fault)
+	}
+	return faults, nil
+}
@@ -0,0 +1,243 @@
+package gosolarman
+
+import (
+	"context"
+	"encoding/binary"
+	"fmt"
+
+	"github.com/grid-x/modbus"
+)
+
+// ExceptionError is returned by the context-aware client methods when the
+// device answers with a Modbus exception.
+type ExceptionError struct {
+	FunctionCode  byte // Function code of the request.
+	ExceptionCode byte // Modbus exception code returned by the device.
+}
+
+// Error implements the error interface.
+func (e *ExceptionError) Error() string {
+	return fmt.Sprintf("modbus exception 0x%02X for function 0x%02X", e.ExceptionCode, e.FunctionCode)
+}
+
+// ContextClient is a Modbus client for Solarman devices that adds
+// context-aware variants of the modbus.Client methods. The embedded
+// modbus.Client methods remain available and use the handler's Timeout.
+type ContextClient struct {
+	modbus.Client
+	handler *SolarmanClientHandler
+}
+
+// NewContextClient creates a new context-aware Modbus client on top of a
+// Solarman client handler.
+//
+// Parameters:
+//   - handler: The Solarman client handler to send requests through.
+//
+// Returns:
+//   - A pointer to the created ContextClient.
+func NewContextClient(handler *SolarmanClientHandler) *ContextClient {
+	return &ContextClient{
+		Client:  modbus.NewClient(handler),
+		handler: handler,
+	}
+}
+
+// NewSolarmanContextClient creates a new context-aware Modbus client for Solarman devices.
+//
+// Parameters:
+//   - Address: The address of the Solarman device (e.g., "192.168.1.1:8899").
+//   - LoggerSerial: The serial number of the data logging stick.
+//   - SlaveID: The Modbus slave ID.
+//
+// Returns:
+//   - A pointer to the created ContextClient.
+func NewSolarmanContextClient(Address string, LoggerSerial uint32, SlaveID byte) *ContextClient {
+	handler := NewSolarmanClientHandler(Address, LoggerSerial)
+	handler.SlaveID = SlaveID
+	return NewContextClient(handler)
+}
+
+// ReadCoilsContext reads from 1 to 2000 contiguous coils.
+func (c *ContextClient) ReadCoilsContext(ctx context.Context, address, quantity uint16) (results []byte, err error) {
+	return c.read(ctx, modbus.FuncCodeReadCoils, address, quantity)
+}
+
+// ReadDiscreteInputsContext reads from 1 to 2000 contiguous discrete inputs.
+func (c *ContextClient) ReadDiscreteInputsContext(ctx context.Context, address, quantity uint16) (results []byte, err error) {
+	return c.read(ctx, modbus.FuncCodeReadDiscreteInputs, address, quantity)
+}
+
+// ReadHoldingRegistersContext reads from 1 to 125 contiguous holding registers.
+func (c *ContextClient) ReadHoldingRegistersContext(ctx context.Context, address, quantity uint16) (results []byte, err error) {
+	return c.read(ctx, modbus.FuncCodeReadHoldingRegisters, address, quantity)
+}
+
+// ReadInputRegistersContext reads from 1 to 125 contiguous input registers.
+func (c *ContextClient) ReadInputRegistersContext(ctx context.Context, address, quantity uint16) (results []byte, err error) {
+	return c.read(ctx, modbus.FuncCodeReadInputRegisters, address, quantity)
+}
+
+// WriteSingleCoilContext writes a single coil to either ON (0xFF00) or OFF (0x0000).
+func (c *ContextClient) WriteSingleCoilContext(ctx context.Context, address, value uint16) (results []byte, err error) {
+	return c.write(ctx, modbus.FuncCodeWriteSingleCoil, dataBlock(address, value))
+}
+
+// WriteSingleRegisterContext writes a single holding register.
+func (c *ContextClient) WriteSingleRegisterContext(ctx context.Context, address, value uint16) (results []byte, err error) {
+	return c.write(ctx, modbus.FuncCodeWriteSingleRegister, dataBlock(address, value))
+}
+
+// WriteMultipleCoilsContext forces each coil in a sequence of coils to either ON or OFF.
+func (c *ContextClient) WriteMultipleCoilsContext(ctx context.Context, address, quantity uint16, value []byte) (results []byte, err error) {
+	return c.write(ctx, modbus.FuncCodeWriteMultipleCoils, dataBlockSuffix(value, address, quantity))
+}
+
+// WriteMultipleRegistersContext writes a block of contiguous registers.
+func (c *ContextClient) WriteMultipleRegistersContext(ctx context.Context, address, quantity uint16, value []byte) (results []byte, err error) {
+	return c.write(ctx, modbus.FuncCodeWriteMultipleRegisters, dataBlockSuffix(value, address, quantity))
+}
+
+// SendContext sends a raw Modbus PDU to the device and returns the response PDU.
+//
+// Parameters:
+//   - ctx: The context controlling the exchange.
+//   - pdu: The Modbus Protocol Data Unit to send.
+//
+// Returns:
+//   - response: The Modbus Protocol Data Unit received from the device.
+//   - err: An ExceptionError if the device answered with an exception, or
+//     another error if the exchange fails.
+func (c *ContextClient) SendContext(ctx context.Context, pdu *modbus.ProtocolDataUnit) (response *modbus.ProtocolDataUnit, err error) {
+	ctx, span := c.handler.startSpan(ctx, SpanRequest)
+	defer func() {
+		span.End(err)
+	}()
+
+	aduRequest, err := c.handler.Encode(pdu)
+	if err != nil {
+		return nil, err
+	}
+	span.SetRequest(aduRequest)
+	aduResponse, err := c.handler.SendContext(ctx, aduRequest)
+	if err != nil {
+		return nil, err
+	}
+	return c.response(ctx, pdu, aduRequest, aduResponse)
+}
+
+// response verifies and decodes the response to a request.
+//
+// Parameters:
+//   - ctx: The context carrying the span of the request.
+//   - pdu: The Modbus Protocol Data Unit that was sent.
+//   - aduRequest: The encoded request.
+//   - aduResponse: The received response.
+//
+// Returns:
+//   - response: The Modbus Protocol Data Unit received from the device.
+//   - err: An ExceptionError if the device answered with an exception, or
+//     another error if the response is invalid.
+func (c *ContextClient) response(ctx context.Context, pdu *modbus.ProtocolDataUnit, aduRequest, aduResponse []byte) (response *modbus.ProtocolDataUnit, err error) {
+	if err = c.handler.Verify(aduRequest, aduResponse); err != nil {
+		return nil, fmt.Errorf("invalid response from %q: %w", c.handler.Address, err)
+	}
+
+	if response, err = c.handler.Decode(aduResponse); err != nil {
+		return nil, fmt.Errorf("failed to decode response from %q: %w", c.handler.Address, err)
+	}
+
+	if response.FunctionCode != pdu.FunctionCode {
+		exception := &ExceptionError{FunctionCode: pdu.FunctionCode}
+		if len(response.Data) > 0 {
+			exception.ExceptionCode = response.Data[0]
+		}
+		return nil, exception
+	}
+	if len(response.Data) == 0 {
+		return nil, fmt.Errorf("response data is empty")
+	}
+	return response, nil
+}
+
+// read sends a read request and validates the byte count of the response.
+//
+// Parameters:
+//   - ctx: The context controlling the exchange.
+//   - functionCode: The Modbus function code of the read request.
+//   - address: The first address to read.
+//   - quantity: The number of items to read.
+//
+// Returns:
+//   - results: The data bytes of the response without the byte count.
+//   - err: An error if the exchange or validation fails.
+func (c *ContextClient) read(ctx context.Context, functionCode byte, address, quantity uint16) (results []byte, err error) {
+	response, err := c.SendContext(ctx, &modbus.ProtocolDataUnit{
+		FunctionCode: functionCode,
+		Data:         dataBlock(address, quantity),
+	})
+	if err != nil {
+		return nil, err
+	}
+	count := int(response.Data[0])
+	if length := len(response.Data) - 1; count != length {
+		return nil, fmt.Errorf("response data size %d does not match count %d", length, count)
+	}
+	return response.Data[1:], nil
+}
+
+// write sends a write request and validates that the response echoes the address.
+//
+// Parameters:
+//   - ctx: The context controlling the exchange.
+//   - functionCode: The Modbus function code of the write request.
+//   - data: The request data starting with the big-endian address.
+//
+// Returns:
+//   - results: The response data following the address.
+//   - err: An error if the exchange or validation fails.
+func (c *ContextClient) write(ctx context.Context, functionCode byte, data []byte) (results []byte, err error) {
+	response, err := c.SendContext(ctx, &modbus.ProtocolDataUnit{
+		FunctionCode: functionCode,
+		Data:         data,
+	})
+	if err != nil {
+		return nil, err
+	}
+	if len(response.Data) != 4 {
+		return nil, fmt.Errorf("response data size %d does not match expected 4", len(response.Data))
+	}
+	if address := binary.BigEndian.Uint16(response.Data); address != binary.BigEndian.Uint16(data) {
+		return nil, fmt.Errorf("response address %d does not match request %d", address, binary.BigEndian.Uint16(data))
+	}
+	return response.Data[2:], nil
+}
+
+// dataBlock creates a sequence of big-endian uint16 values.
+//
+// Parameters:
+//   - value: The values to encode.
+//
+// Returns:
+//   - A byte array holding the encoded values.
+func dataBlock(value ...uint16) []byte {
+	data := make([]byte, 2*len(value))
+	for i, v := range value {
+		binary.BigEndian.PutUint16(data[i*2:], v)
+	}
+	return data
+}
+
+// dataBlockSuffix creates a sequence of big-endian uint16 values followed by
+// the length of suffix and suffix itself.
+//
+// Parameters:
+//   - suffix: The bytes to append after the values.
+//   - value: The values to encode.
+//
+// Returns:
+//   - A byte array holding the encoded values and suffix.
+func dataBlockSuffix(suffix []byte, value ...uint16) []byte {
+	data := append(dataBlock(value...), byte(len(suffix)))
+	return append(data, suffix...)
+}
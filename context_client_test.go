@@ -0,0 +1,105 @@
+package gosolarman
+
+import (
+	"context"
+	"errors"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/grid-x/modbus"
+)
+
+// respond reads one request from conn and answers it with a read holding
+// registers response carrying the given register values.
+func respond(t *testing.T, conn net.Conn, registers ...uint16) {
+	t.Helper()
+	request, err := newFrameReader(conn).ReadFrame()
+	if err != nil {
+		t.Errorf("failed to read request: %v", err)
+		return
+	}
+	if _, err := conn.Write(responseFrame(request, registers...)); err != nil {
+		t.Errorf("failed to write response: %v", err)
+	}
+}
+
+// responseFrame builds the response to a read request carrying registers.
+func responseFrame(request []byte, registers ...uint16) []byte {
+	data := append([]byte{byte(2 * len(registers))}, dataBlock(registers...)...)
+	return rtuResponseFrame(request, request[27], data)
+}
+
+// rtuResponseFrame builds the response to a request carrying an RTU frame
+// with the given function code and data.
+func rtuResponseFrame(request []byte, functionCode byte, data []byte) []byte {
+	rtu := append([]byte{request[26], functionCode}, data...)
+	rtu = append(rtu, CRCFromBytes(rtu)...)
+
+	response := []byte{StartByte, 0, 0, 0x10, 0x15, request[5], request[6]}
+	response = append(response, request[7:11]...)
+	response = append(response, 0x02, 0x01)
+	response = append(response, make([]byte, 12)...)
+	response = append(response, rtu...)
+	response[1] = byte(len(response) - 11)
+	return append(response, CheckSum(response[1:]), EndByte)
+}
+
+func newPipeClient() (*ContextClient, net.Conn) {
+	client, server := net.Pipe()
+	handler := NewSolarmanClientHandler("pipe", 0x12345678)
+	handler.SlaveID = 0x01
+	handler.conn = client
+	return NewContextClient(handler), server
+}
+
+func TestReadHoldingRegistersContext(t *testing.T) {
+	client, server := newPipeClient()
+	defer server.Close()
+	go respond(t, server, 0x0271, 0x0001)
+
+	results, err := client.ReadHoldingRegistersContext(context.Background(), 625, 2)
+	if err != nil {
+		t.Fatalf("ReadHoldingRegistersContext failed: %v", err)
+	}
+	expected := []byte{0x02, 0x71, 0x00, 0x01}
+	if string(results) != string(expected) {
+		t.Errorf("Expected results %X, got %X", expected, results)
+	}
+}
+
+func TestSendContextDeadline(t *testing.T) {
+	client, server := newPipeClient()
+	defer server.Close()
+	go newFrameReader(server).ReadFrame() // Accept the request but never answer.
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	_, err := client.ReadHoldingRegistersContext(ctx, 625, 1)
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("Expected context.DeadlineExceeded, got %v", err)
+	}
+	if client.handler.conn != nil {
+		t.Errorf("Expected connection to be reset after deadline")
+	}
+}
+
+func TestSendContextCancel(t *testing.T) {
+	client, server := newPipeClient()
+	defer server.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	go func() {
+		newFrameReader(server).ReadFrame()
+		cancel()
+	}()
+
+	_, err := client.SendContext(ctx, &modbus.ProtocolDataUnit{
+		FunctionCode: modbus.FuncCodeReadHoldingRegisters,
+		Data:         dataBlock(625, 1),
+	})
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("Expected context.Canceled, got %v", err)
+	}
+}
@@ -0,0 +1,130 @@
+package gosolarman
+
+import (
+	"errors"
+	"fmt"
+)
+
+var (
+	// ErrShortFrame is returned when a frame is shorter than its fixed fields require.
+	ErrShortFrame = errors.New("frame too short")
+
+	// ErrBadStartByte is returned when a frame does not begin with StartByte.
+	ErrBadStartByte = errors.New("invalid start byte")
+
+	// ErrBadEndByte is returned when a frame does not end with EndByte.
+	ErrBadEndByte = errors.New("invalid end byte")
+
+	// ErrLengthMismatch is returned when the Length field of the header does
+	// not match the size of the payload.
+	ErrLengthMismatch = errors.New("length mismatch")
+
+	// ErrChecksum is returned when the checksum of a frame is wrong.
+	ErrChecksum = errors.New("checksum mismatch")
+
+	// ErrCRC is returned when the CRC of the Modbus RTU frame is wrong.
+	ErrCRC = errors.New("CRC mismatch")
+
+	// ErrSequenceMismatch is returned when the sequence number of a response
+	// does not match the request.
+	ErrSequenceMismatch = errors.New("sequence number mismatch")
+
+	// ErrControlCode is returned when a frame has an unexpected control code.
+	ErrControlCode = errors.New("control code mismatch")
+
+	// ErrLoggerSerialMismatch is returned when the logger serial number of a
+	// response does not match the request.
+	ErrLoggerSerialMismatch = errors.New("logger serial number mismatch")
+
+	// ErrInverterUnreachable is returned when the data logging stick answers
+	// with a valid frame that carries no Modbus RTU frame, e.g. because the
+	// inverter is asleep or its RS485 link is down.
+	ErrInverterUnreachable = errors.New("inverter not responding")
+
+	// ErrReplayMismatch is returned by a ReplayTransport when a request does
+	// not match the next recorded request.
+	ErrReplayMismatch = errors.New("request does not match recording")
+
+	// ErrReplayExhausted is returned by a ReplayTransport when all recorded
+	// exchanges have been replayed.
+	ErrReplayExhausted = errors.New("recording exhausted")
+
+	// errConnect is wrapped by the errors of exchanges that failed because
+	// no connection to the data logging stick could be established.
+	errConnect = errors.New("failed to connect")
+
+	// errSlaveIDMismatch is returned by a gateway when the Modbus RTU frame of
+	// a response comes from another slave than the request was sent to.
+	errSlaveIDMismatch = errors.New("slave ID mismatch")
+)
+
+// FrameError describes a protocol failure while parsing or verifying a frame.
+// It wraps one of the sentinel errors, so it can be tested with errors.Is,
+// and it can be extracted with errors.As to inspect the offending values.
+type FrameError struct {
+	Err      error  // The sentinel error describing the failure (e.g., ErrChecksum).
+	Expected uint32 // The expected value (e.g., the calculated checksum or minimum length).
+	Got      uint32 // The value found in the frame.
+	Frame    []byte // The raw frame that failed.
+}
+
+// newFrameError creates a new FrameError.
+//
+// Parameters:
+//   - err: The sentinel error describing the failure.
+//   - expected: The expected value.
+//   - got: The value found in the frame.
+//   - frame: The raw frame that failed.
+//
+// Returns:
+//   - A pointer to the created FrameError.
+func newFrameError(err error, expected, got uint32, frame []byte) *FrameError {
+	return &FrameError{
+		Err:      err,
+		Expected: expected,
+		Got:      got,
+		Frame:    frame,
+	}
+}
+
+// Error implements the error interface.
+func (e *FrameError) Error() string {
+	switch e.Err {
+	case ErrShortFrame:
+		return fmt.Sprintf("%v: expected at least %d bytes, got %d", e.Err, e.Expected, e.Got)
+	case ErrLengthMismatch:
+		return fmt.Sprintf("%v: expected %d, got %d", e.Err, e.Expected, e.Got)
+	default:
+		return fmt.Sprintf("%v: expected 0x%02X, got 0x%02X", e.Err, e.Expected, e.Got)
+	}
+}
+
+// Unwrap returns the sentinel error.
+func (e *FrameError) Unwrap() error {
+	return e.Err
+}
+
+// InverterError is returned when the data logging stick replies without a
+// Modbus RTU frame from the inverter. It wraps ErrInverterUnreachable and
+// carries the logger fields of the reply.
+type InverterError struct {
+	Status           byte   // Status byte reported by the data logging stick.
+	TotalWorkingTime uint32 // Total working time of the data logging stick in seconds.
+	PowerOnTime      uint32 // Current uptime of the data logging stick in seconds.
+	OffsetTime       uint32 // Offset timestamp in seconds.
+	Code             []byte // Payload following the timing fields (e.g., a logger error code).
+	Frame            []byte // The raw frame, set by Parse (nil if the payload was decoded on its own).
+}
+
+// Error implements the error interface.
+func (e *InverterError) Error() string {
+	if len(e.Code) > 0 {
+		return fmt.Sprintf("%v: logger status 0x%02X, code 0x%X", ErrInverterUnreachable, e.Status, e.Code)
+	}
+	return fmt.Sprintf("%v: logger status 0x%02X", ErrInverterUnreachable, e.Status)
+}
+
+// Unwrap returns ErrInverterUnreachable.
+func (e *InverterError) Unwrap() error {
+	return ErrInverterUnreachable
+}
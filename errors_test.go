@@ -0,0 +1,94 @@
+package gosolarman
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+)
+
+func TestParseErrors(t *testing.T) {
+	valid := []byte{
+		0xA5, 0x16, 0x00, 0x10, 0x45, 0x01, 0x02, 0x12, 0x34, 0x56, 0x78,
+		0x02, 0x01, 0x10, 0x00, 0x00, 0x00, 0x20, 0x00, 0x00, 0x00, 0x30, 0x00, 0x00, 0x00,
+		0x01, 0x03, 0x02, 0x71, 0x00, 0x01, 0xd5, 0xa9,
+		0xDB, 0x15,
+	}
+
+	tests := []struct {
+		name     string
+		modify   func(data []byte) []byte
+		expected error
+	}{
+		{"short", func(data []byte) []byte { return data[:5] }, ErrShortFrame},
+		{"start", func(data []byte) []byte { data[0] = 0xA6; return data }, ErrBadStartByte},
+		{"end", func(data []byte) []byte { data[len(data)-1] = 0x16; return data }, ErrBadEndByte},
+		{"checksum", func(data []byte) []byte { data[len(data)-2]++; return data }, ErrChecksum},
+		{"length", func(data []byte) []byte { data[1]++; data[len(data)-2]++; return data }, ErrLengthMismatch},
+		{"crc", func(data []byte) []byte { data[31]++; data[len(data)-2]++; return data }, ErrCRC},
+	}
+	for _, test := range tests {
+		data := test.modify(append([]byte(nil), valid...))
+		_, err := Parse(data)
+		if !errors.Is(err, test.expected) {
+			t.Errorf("%s: expected %v, got %v", test.name, test.expected, err)
+		}
+		var frameErr *FrameError
+		if !errors.As(err, &frameErr) {
+			t.Errorf("%s: expected FrameError, got %T", test.name, err)
+		}
+	}
+}
+
+func TestVerifyErrors(t *testing.T) {
+	packager := &solarmanPackager{}
+	aduRequest := []byte{0xA5, 0x0E, 0x00, 0x10, 0x45, 0x01, 0x00, 0x4F, 0xAD, 0x6D, 0xA5}
+	aduResponse := []byte{0xA5, 0x0E, 0x00, 0x10, 0x15, 0x01, 0x00, 0x4F, 0xAD, 0x6D, 0xA6, 0x43, 0x15}
+
+	err := packager.Verify(aduRequest, aduResponse)
+	var frameErr *FrameError
+	if !errors.As(err, &frameErr) || !errors.Is(err, ErrLoggerSerialMismatch) {
+		t.Fatalf("Expected logger serial number mismatch, got %v", err)
+	}
+	if frameErr.Expected != 0xA56DAD4F || frameErr.Got != 0xA66DAD4F {
+		t.Errorf("Expected 0xA56DAD4F and 0xA66DAD4F, got 0x%08X and 0x%08X", frameErr.Expected, frameErr.Got)
+	}
+
+	aduResponse[4], aduResponse[11] = 0x47, 0x75
+	if err := packager.Verify(aduRequest, aduResponse); !errors.Is(err, ErrControlCode) {
+		t.Errorf("Expected control code mismatch, got %v", err)
+	}
+}
+
+func TestParseInverterUnreachable(t *testing.T) {
+	data := []byte{
+		0xA5, 0x10, 0x00, 0x10, 0x15, 0x01, 0x02, 0x12, 0x34, 0x56, 0x78,
+		0x02, 0x01, 0x10, 0x00, 0x00, 0x00, 0x20, 0x00, 0x00, 0x00, 0x30, 0x00, 0x00, 0x00,
+		0x05, 0x00,
+		0x00, 0x15,
+	}
+	data[len(data)-2] = CheckSum(data[1 : len(data)-2])
+
+	_, err := Parse(data)
+	if !errors.Is(err, ErrInverterUnreachable) {
+		t.Fatalf("Expected ErrInverterUnreachable, got %v", err)
+	}
+	var inverterErr *InverterError
+	if !errors.As(err, &inverterErr) {
+		t.Fatalf("Expected InverterError, got %T", err)
+	}
+	if inverterErr.Status != 0x01 || inverterErr.PowerOnTime != 0x20 || inverterErr.OffsetTime != 0x30 {
+		t.Errorf("Unexpected logger fields %+v", inverterErr)
+	}
+	if !bytes.Equal(inverterErr.Code, []byte{0x05, 0x00}) {
+		t.Errorf("Expected code 0500, got %X", inverterErr.Code)
+	}
+	if !bytes.Equal(inverterErr.Frame, data) {
+		t.Errorf("Expected the whole frame, got %X", inverterErr.Frame)
+	}
+
+	// Decoding the payload on its own leaves the frame unset.
+	err = (&ResponsePayload{}).UnmarshalBinary(data[11 : len(data)-2])
+	if !errors.As(err, &inverterErr) || inverterErr.Frame != nil {
+		t.Errorf("Expected an InverterError without frame, got %v", err)
+	}
+}
@@ -0,0 +1,115 @@
+package gosolarman
+
+import (
+	"errors"
+	"time"
+)
+
+// FrameInfo holds the logger metadata of a response frame.
+type FrameInfo struct {
+	ReceivedAt         time.Time // Time at which the response was decoded.
+	SequenceNumber     uint16    // Sequence number of the response.
+	LoggerSerialNumber uint32    // Serial number of the data logging stick.
+	FrameType          byte      // Frame type (e.g., 0x02 for solar inverter).
+	Status             byte      // Status reported by the data logging stick.
+	TotalWorkingTime   uint32    // Total working time of the data logging stick in seconds.
+	PowerOnTime        uint32    // Current uptime of the data logging stick in seconds.
+	OffsetTime         uint32    // Offset timestamp in seconds.
+	InverterReachable  bool      // Whether the response carried a Modbus RTU frame.
+}
+
+// LoggerTime returns the clock of the data logging stick at the time of the
+// response, which is the sum of OffsetTime and PowerOnTime.
+//
+// Returns:
+//   - The clock of the data logging stick, and false if it does not report one.
+func (i FrameInfo) LoggerTime() (time.Time, bool) {
+	if i.OffsetTime == 0 {
+		return time.Time{}, false
+	}
+	return time.Unix(int64(i.OffsetTime)+int64(i.PowerOnTime), 0), true
+}
+
+// Drift returns the difference between the clock of the data logging stick
+// and the local clock at the time of the response.
+//
+// Returns:
+//   - The drift (positive if the logger is ahead), and false if the logger does not report its clock.
+func (i FrameInfo) Drift() (time.Duration, bool) {
+	loggerTime, ok := i.LoggerTime()
+	if !ok {
+		return 0, false
+	}
+	return loggerTime.Sub(i.ReceivedAt), true
+}
+
+// LastFrameInfo returns the logger metadata of the most recently decoded response.
+//
+// Returns:
+//   - The metadata, and false if no response has been decoded yet.
+func (mb *solarmanPackager) LastFrameInfo() (info FrameInfo, ok bool) {
+	if last := mb.lastFrameInfo.Load(); last != nil {
+		return *last, true
+	}
+	return FrameInfo{}, false
+}
+
+// recordFrameInfo stores the logger metadata of a decoded response and passes
+// it to OnFrameInfo.
+//
+// Parameters:
+//   - response: The parsed response, or nil if parsing failed.
+//   - err: The error returned by Parse.
+func (mb *solarmanPackager) recordFrameInfo(response *Response, err error) {
+	info, ok := newFrameInfo(response, err)
+	if !ok {
+		return
+	}
+	mb.lastFrameInfo.Store(&info)
+	if mb.OnFrameInfo != nil {
+		mb.OnFrameInfo(info)
+	}
+}
+
+// newFrameInfo extracts the logger metadata from the result of Parse.
+//
+// Parameters:
+//   - response: The parsed response, or nil if parsing failed.
+//   - err: The error returned by Parse.
+//
+// Returns:
+//   - The metadata, and false if neither the response nor an InverterError carries it.
+func newFrameInfo(response *Response, err error) (info FrameInfo, ok bool) {
+	if err == nil {
+		return FrameInfo{
+			ReceivedAt:         time.Now(),
+			SequenceNumber:     response.Header.SequenceNumber,
+			LoggerSerialNumber: response.Header.LoggerSerialNumber,
+			FrameType:          response.Payload.FrameType,
+			Status:             response.Payload.Status,
+			TotalWorkingTime:   response.Payload.TotalWorkingTime,
+			PowerOnTime:        response.Payload.PowerOnTime,
+			OffsetTime:         response.Payload.OffsetTime,
+			InverterReachable:  true,
+		}, true
+	}
+
+	var inverterErr *InverterError
+	if !errors.As(err, &inverterErr) {
+		return FrameInfo{}, false
+	}
+	header, headerErr := ParseHeader(inverterErr.Frame)
+	if headerErr != nil {
+		return FrameInfo{}, false
+	}
+	return FrameInfo{
+		ReceivedAt:         time.Now(),
+		SequenceNumber:     header.SequenceNumber,
+		LoggerSerialNumber: header.LoggerSerialNumber,
+		FrameType:          inverterErr.Frame[headerLength],
+		Status:             inverterErr.Status,
+		TotalWorkingTime:   inverterErr.TotalWorkingTime,
+		PowerOnTime:        inverterErr.PowerOnTime,
+		OffsetTime:         inverterErr.OffsetTime,
+	}, true
+}
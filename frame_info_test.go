@@ -0,0 +1,44 @@
+package gosolarman
+
+import (
+	"encoding/hex"
+	"errors"
+	"testing"
+)
+
+func TestDecodeRecordsFrameInfo(t *testing.T) {
+	packager := &solarmanPackager{}
+	if _, ok := packager.LastFrameInfo(); ok {
+		t.Fatalf("Expected no frame info before the first response")
+	}
+	var hooked []FrameInfo
+	packager.OnFrameInfo = func(info FrameInfo) {
+		hooked = append(hooked, info)
+	}
+
+	adu, _ := hex.DecodeString("a517001015012ad302964902013412000058020000f3a01265010304000102716ab76515")
+	if _, err := packager.Decode(adu); err != nil {
+		t.Fatalf("Decode failed: %v", err)
+	}
+	info, ok := packager.LastFrameInfo()
+	if !ok {
+		t.Fatalf("Expected frame info after a response")
+	}
+	if info.SequenceNumber != 0x2A01 || info.LoggerSerialNumber != 1234567891 || info.Status != 0x01 ||
+		info.TotalWorkingTime != 0x1234 || info.PowerOnTime != 0x0258 || info.OffsetTime != 0x6512A0F3 || !info.InverterReachable {
+		t.Errorf("Unexpected frame info %+v", info)
+	}
+
+	adu, _ = hex.DecodeString("a510001015032cd302964902013412000058020000f3a012650500ca15")
+	if _, err := packager.Decode(adu); !errors.Is(err, ErrInverterUnreachable) {
+		t.Fatalf("Expected ErrInverterUnreachable, got %v", err)
+	}
+	info, _ = packager.LastFrameInfo()
+	if info.SequenceNumber != 0x2C03 || info.FrameType != 0x02 || info.PowerOnTime != 0x0258 || info.InverterReachable {
+		t.Errorf("Unexpected frame info %+v", info)
+	}
+
+	if len(hooked) != 2 {
+		t.Errorf("Expected OnFrameInfo to be called twice, got %d", len(hooked))
+	}
+}
@@ -0,0 +1,94 @@
+package gosolarman
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"testing"
+)
+
+// chunkedReader returns the underlying data in chunks of the given sizes.
+type chunkedReader struct {
+	data   []byte
+	chunks []int
+}
+
+func (c *chunkedReader) Read(b []byte) (n int, err error) {
+	if len(c.data) == 0 {
+		return 0, io.EOF
+	}
+	size := len(c.data)
+	if len(c.chunks) > 0 {
+		size = min(c.chunks[0], size)
+		c.chunks = c.chunks[1:]
+	}
+	n = copy(b, c.data[:size])
+	c.data = c.data[n:]
+	return n, nil
+}
+
+var testFrame = []byte{
+	0xA5,       // Start
+	0x16, 0x00, // Length (22 bytes)
+	0x10, 0x15, // Control Code
+	0x01, 0x02, // Sequence Number
+	0x12, 0x34, 0x56, 0x78, // Logger Serial Number
+	0x02,                   // Frame Type
+	0x01,                   // Status
+	0x10, 0x00, 0x00, 0x00, // Total Working Time
+	0x20, 0x00, 0x00, 0x00, // Power On Time
+	0x30, 0x00, 0x00, 0x00, // Offset Time
+	0x01, 0x03, 0x02, 0x71, 0x00, 0x01, 0xd5, 0xa9, // Modbus RTU Frame
+	0xDB, // Checksum
+	0x15, // End
+}
+
+func TestReadFrameSplit(t *testing.T) {
+	reader := newFrameReader(&chunkedReader{data: testFrame, chunks: []int{1, 5, 7, 3}})
+
+	frame, err := reader.ReadFrame()
+	if err != nil {
+		t.Fatalf("ReadFrame failed: %v", err)
+	}
+	if !bytes.Equal(frame, testFrame) {
+		t.Errorf("Expected frame %X, got %X", testFrame, frame)
+	}
+}
+
+func TestReadFrameCoalesced(t *testing.T) {
+	data := append(append([]byte{}, testFrame...), testFrame...)
+	reader := newFrameReader(&chunkedReader{data: data})
+
+	for i := range 2 {
+		frame, err := reader.ReadFrame()
+		if err != nil {
+			t.Fatalf("ReadFrame %d failed: %v", i, err)
+		}
+		if !bytes.Equal(frame, testFrame) {
+			t.Errorf("Expected frame %d %X, got %X", i, testFrame, frame)
+		}
+	}
+}
+
+func TestReadFrameResync(t *testing.T) {
+	data := []byte{0x00, 0xFF, 0xA5, 0xFF, 0xFF, 0xA5, 0x01, 0x00, 0x10}
+	data = append(data, testFrame...)
+	reader := newFrameReader(&chunkedReader{data: data})
+
+	frame, err := reader.ReadFrame()
+	if err != nil {
+		t.Fatalf("ReadFrame failed: %v", err)
+	}
+	if !bytes.Equal(frame, testFrame) {
+		t.Errorf("Expected frame %X, got %X", testFrame, frame)
+	}
+}
+
+func TestReadFrameTruncated(t *testing.T) {
+	reader := newFrameReader(&chunkedReader{data: testFrame[:20]})
+
+	_, err := reader.ReadFrame()
+	if !errors.Is(err, io.ErrUnexpectedEOF) {
+		t.Errorf("Expected io.ErrUnexpectedEOF, got %v", err)
+	}
+}
@@ -0,0 +1,50 @@
+package gosolarman
+
+import (
+	"testing"
+)
+
+// The seed corpus in testdata/fuzz consists of synthetic frames, built by
+// hand in the format of a data logging stick. No captures of real sticks are
+// available yet; add them next to the synthetic seeds when they are.
+
+func FuzzParse(f *testing.F) {
+	f.Add([]byte{0xA5, 0x15, 0x00, 0x10, 0x15, 0x01, 0x02, 0x12, 0x34, 0x56, 0x78,
+		0x02, 0x01, 0x10, 0x00, 0x00, 0x00, 0x20, 0x00, 0x00, 0x00, 0x30, 0x00, 0x00, 0x00,
+		0x01, 0x03, 0x02, 0x00, 0x01, 0x79, 0x84, 0xB8, 0x15})
+	f.Add([]byte{0xA5, 0x00, 0x00, 0x10, 0x15, 0x01, 0x00, 0x4F, 0xAD, 0x6D, 0xA5, 0x00, 0x15})
+
+	f.Fuzz(func(t *testing.T, data []byte) {
+		response, err := Parse(data)
+		if err != nil {
+			return
+		}
+		if int(response.Header.Length) != len(data)-headerLength-trailerLength {
+			t.Errorf("Length %d does not match frame size %d", response.Header.Length, len(data))
+		}
+		if _, err := (&solarmanPackager{}).Decode(data); err != nil {
+			t.Errorf("Decode failed after Parse succeeded: %v", err)
+		}
+	})
+}
+
+func FuzzParseHeader(f *testing.F) {
+	f.Add([]byte{0xA5, 0x0E, 0x00, 0x10, 0x45, 0x01, 0x02, 0x12, 0x34, 0x56, 0x78})
+
+	f.Fuzz(func(t *testing.T, data []byte) {
+		header, err := ParseHeader(data)
+		if err == nil && header.Start != StartByte {
+			t.Errorf("Expected Start 0x%02X, got 0x%02X", StartByte, header.Start)
+		}
+	})
+}
+
+func FuzzVerify(f *testing.F) {
+	f.Add([]byte{0xA5, 0x0E, 0x00, 0x10, 0x45, 0x01, 0x00, 0x4F, 0xAD, 0x6D, 0xA5},
+		[]byte{0xA5, 0x0E, 0x00, 0x10, 0x15, 0x01, 0x00, 0x4F, 0xAD, 0x6D, 0xA5, 0x42, 0x15})
+
+	f.Fuzz(func(t *testing.T, aduRequest []byte, aduResponse []byte) {
+		(&solarmanPackager{}).Verify(aduRequest, aduResponse)
+		(&solarmanPackager{LooseSequence: true}).Verify(aduRequest, aduResponse)
+	})
+}
@@ -0,0 +1,302 @@
+package gosolarman
+
+import (
+	"bufio"
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net"
+	"os"
+
+	"github.com/grid-x/modbus"
+)
+
+const (
+	// mbapHeaderLength is the length of the MBAP header of a Modbus TCP frame,
+	// including the unit ID.
+	mbapHeaderLength = 7
+
+	// maxPDULength is the maximum length of a Modbus PDU.
+	maxPDULength = 253
+)
+
+// gateway forwards Modbus PDUs received from clients of another Modbus
+// protocol to the slaves behind a data logging stick.
+type gateway struct {
+	handler *SolarmanClientHandler // Connection to the data logging stick.
+	server  *server
+
+	// UnitIDs maps the unit IDs of requests to Modbus slave IDs. If nil, the
+	// unit ID is used as slave ID, except for 0 and 0xFF which address the
+	// SlaveID of the handler. Requests for unit IDs missing from a non-nil
+	// map are answered with a gateway path unavailable exception. UnitIDs
+	// must not be modified while the gateway is serving.
+	UnitIDs map[byte]byte
+}
+
+// Close stops accepting clients, disconnects all clients and aborts the
+// request in progress. The handler is not closed.
+//
+// Returns:
+//   - An error if closing a listener fails.
+func (g *gateway) Close() error {
+	return g.server.close()
+}
+
+// slaveID returns the Modbus slave ID that a unit ID maps to.
+//
+// Parameters:
+//   - unitID: The unit ID of the request.
+//
+// Returns:
+//   - slaveID: The Modbus slave ID.
+//   - ok: false if the unit ID is not mapped.
+func (g *gateway) slaveID(unitID byte) (slaveID byte, ok bool) {
+	if g.UnitIDs != nil {
+		slaveID, ok = g.UnitIDs[unitID]
+		return slaveID, ok
+	}
+	if unitID == 0 || unitID == 0xFF {
+		return g.handler.slaveID(), true
+	}
+	return unitID, true
+}
+
+// forward sends a request PDU to the slave that a unit ID maps to and
+// returns the response PDU. Failures are answered with an exception PDU.
+//
+// Parameters:
+//   - ctx: The context controlling the exchange.
+//   - client: The address of the client, for logging.
+//   - unitID: The unit ID of the request.
+//   - pdu: The request PDU.
+//
+// Returns:
+//   - The response or exception PDU.
+func (g *gateway) forward(ctx context.Context, client string, unitID byte, pdu *modbus.ProtocolDataUnit) *modbus.ProtocolDataUnit {
+	slaveID, ok := g.slaveID(unitID)
+	if !ok {
+		g.handler.log(ctx, slog.LevelWarn, "unknown unit ID", slog.String("client", client), slog.Int("unit_id", int(unitID)))
+		return exceptionPDU(pdu.FunctionCode, modbus.ExceptionCodeGatewayPathUnavailable)
+	}
+	response, err := g.handler.sendTo(ctx, slaveID, pdu)
+	if err != nil {
+		code := exceptionCode(err, g.handler.retryPolicy())
+		g.handler.log(ctx, slog.LevelWarn, "forwarding failed",
+			slog.String("client", client),
+			slog.Int("unit_id", int(unitID)),
+			slog.Int("exception_code", int(code)),
+			slog.Any("error", err))
+		return exceptionPDU(pdu.FunctionCode, code)
+	}
+	return response
+}
+
+// sendTo sends a PDU to a slave behind the data logging stick and returns the
+// response PDU, which may be a Modbus exception.
+//
+// Parameters:
+//   - ctx: The context controlling the exchange.
+//   - slaveID: The Modbus slave ID.
+//   - pdu: The Modbus Protocol Data Unit to send.
+//
+// Returns:
+//   - response: The Modbus Protocol Data Unit received from the device.
+//   - err: An error if the exchange fails, the response is invalid or it comes
+//     from another slave.
+func (h *SolarmanClientHandler) sendTo(ctx context.Context, slaveID byte, pdu *modbus.ProtocolDataUnit) (response *modbus.ProtocolDataUnit, err error) {
+	ctx, span := h.startSpan(ctx, SpanRequest)
+	defer func() {
+		span.End(err)
+	}()
+
+	aduRequest, err := h.encode(pdu, slaveID)
+	if err != nil {
+		return nil, err
+	}
+	span.SetRequest(aduRequest)
+	aduResponse, err := h.SendContext(ctx, aduRequest)
+	if err != nil {
+		return nil, err
+	}
+
+	if err = h.Verify(aduRequest, aduResponse); err != nil {
+		return nil, fmt.Errorf("invalid response from %q: %w", h.Address, err)
+	}
+
+	if response, err = h.Decode(aduResponse); err != nil {
+		return nil, fmt.Errorf("failed to decode response from %q: %w", h.Address, err)
+	}
+	if responseSlaveID := aduResponse[headerLength+responsePayloadLength]; responseSlaveID != slaveID {
+		return nil, fmt.Errorf("%w: response from slave %d to request for slave %d", errSlaveIDMismatch, responseSlaveID, slaveID)
+	}
+	if response.FunctionCode&0x7F != pdu.FunctionCode {
+		return nil, fmt.Errorf("response function code 0x%02X does not match request 0x%02X", response.FunctionCode, pdu.FunctionCode)
+	}
+	return response, nil
+}
+
+// exceptionCode returns the Modbus exception code that reports err to a
+// gateway client.
+//
+// Parameters:
+//   - err: The error of the exchange with the data logging stick.
+//   - policy: The RetryPolicy that classifies err.
+//
+// Returns:
+//   - ExceptionCodeGatewayPathUnavailable if no connection to the data logging
+//     stick could be established, ExceptionCodeServerDeviceFailure if it sent
+//     an invalid response, and ExceptionCodeGatewayTargetDeviceFailedToRespond
+//     if no response arrived or the addressed slave did not answer.
+func exceptionCode(err error, policy RetryPolicy) byte {
+	switch {
+	case errors.Is(err, errConnect):
+		return modbus.ExceptionCodeGatewayPathUnavailable
+	case errors.Is(err, ErrInverterUnreachable), errors.Is(err, errSlaveIDMismatch), errors.Is(err, context.DeadlineExceeded):
+		return modbus.ExceptionCodeGatewayTargetDeviceFailedToRespond
+	case policy.Classify(err) == ErrorClassProtocol:
+		return modbus.ExceptionCodeServerDeviceFailure
+	default:
+		return modbus.ExceptionCodeGatewayTargetDeviceFailedToRespond
+	}
+}
+
+// exceptionPDU creates an exception response PDU.
+//
+// Parameters:
+//   - functionCode: The function code of the request.
+//   - code: The Modbus exception code.
+//
+// Returns:
+//   - The exception PDU.
+func exceptionPDU(functionCode byte, code byte) *modbus.ProtocolDataUnit {
+	return &modbus.ProtocolDataUnit{
+		FunctionCode: functionCode | 0x80,
+		Data:         []byte{code},
+	}
+}
+
+// ModbusTCPGateway is a Modbus TCP server that forwards the requests of its
+// clients to the slaves behind a data logging stick, so that tools that only
+// speak Modbus TCP can read the inverter. Requests of concurrent clients are
+// queued on the connection of the handler.
+type ModbusTCPGateway struct {
+	gateway
+}
+
+// NewModbusTCPGateway creates a new Modbus TCP gateway on top of a Solarman client handler.
+//
+// Parameters:
+//   - handler: The handler that forwards the requests to the data logging stick.
+//
+// Returns:
+//   - A pointer to the created ModbusTCPGateway.
+func NewModbusTCPGateway(handler *SolarmanClientHandler) *ModbusTCPGateway {
+	return &ModbusTCPGateway{
+		gateway: gateway{
+			handler: handler,
+			server:  newServer(),
+		},
+	}
+}
+
+// ListenAndServe listens on a TCP address and serves clients until the gateway is closed.
+//
+// Parameters:
+//   - address: The address to listen on (e.g., ":502").
+//
+// Returns:
+//   - An error if listening fails, or net.ErrClosed after Close.
+func (g *ModbusTCPGateway) ListenAndServe(address string) error {
+	listener, err := net.Listen("tcp", address)
+	if err != nil {
+		return err
+	}
+	return g.Serve(listener)
+}
+
+// Serve accepts clients on listener until the gateway is closed.
+//
+// Parameters:
+//   - listener: The listener to accept clients on. It is closed by Serve.
+//
+// Returns:
+//   - An error if accepting fails, or net.ErrClosed after Close.
+func (g *ModbusTCPGateway) Serve(listener net.Listener) error {
+	return g.server.serve(listener, g.serveConn)
+}
+
+// serveConn answers the Modbus TCP requests of one client until it
+// disconnects or sends an invalid frame.
+//
+// Parameters:
+//   - ctx: The context of the gateway, cancelled on Close.
+//   - conn: The connection of the client.
+func (g *ModbusTCPGateway) serveConn(ctx context.Context, conn net.Conn) {
+	client := conn.RemoteAddr().String()
+	reader := bufio.NewReader(conn)
+	for {
+		header, pdu, err := readMBAP(reader)
+		if err != nil {
+			if !errors.Is(err, io.EOF) && !errors.Is(err, net.ErrClosed) && !errors.Is(err, os.ErrDeadlineExceeded) {
+				g.handler.log(ctx, slog.LevelWarn, "invalid Modbus TCP frame", slog.String("client", client), slog.Any("error", err))
+			}
+			return
+		}
+		response := g.forward(ctx, client, header[6], pdu)
+		if ctx.Err() != nil {
+			return
+		}
+		if _, err := conn.Write(mbapFrame(header, response)); err != nil {
+			return
+		}
+	}
+}
+
+// readMBAP reads one Modbus TCP frame.
+//
+// Parameters:
+//   - r: The reader of the client connection.
+//
+// Returns:
+//   - header: The MBAP header of the frame.
+//   - pdu: The request PDU of the frame.
+//   - err: An error if reading fails or the frame is invalid.
+func readMBAP(r io.Reader) (header []byte, pdu *modbus.ProtocolDataUnit, err error) {
+	header = make([]byte, mbapHeaderLength)
+	if _, err = io.ReadFull(r, header); err != nil {
+		return nil, nil, err
+	}
+	if protocol := binary.BigEndian.Uint16(header[2:4]); protocol != 0 {
+		return nil, nil, fmt.Errorf("unsupported protocol ID %d", protocol)
+	}
+	length := int(binary.BigEndian.Uint16(header[4:6]))
+	if length < 2 || length > maxPDULength+1 {
+		return nil, nil, fmt.Errorf("invalid length %d", length)
+	}
+	data := make([]byte, length-1)
+	if _, err = io.ReadFull(r, data); err != nil {
+		return nil, nil, unexpectedEOF(err)
+	}
+	return header, &modbus.ProtocolDataUnit{FunctionCode: data[0], Data: data[1:]}, nil
+}
+
+// mbapFrame creates the Modbus TCP frame of a response.
+//
+// Parameters:
+//   - header: The MBAP header of the request.
+//   - pdu: The response PDU.
+//
+// Returns:
+//   - The response frame.
+func mbapFrame(header []byte, pdu *modbus.ProtocolDataUnit) []byte {
+	frame := make([]byte, mbapHeaderLength, mbapHeaderLength+1+len(pdu.Data))
+	copy(frame, header[:4])
+	binary.BigEndian.PutUint16(frame[4:6], uint16(2+len(pdu.Data)))
+	frame[6] = header[6]
+	frame = append(frame, pdu.FunctionCode)
+	return append(frame, pdu.Data...)
+}
@@ -0,0 +1,250 @@
+package gosolarman
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/grid-x/modbus"
+)
+
+// fakeLogger serves a data logging stick on a local listener that answers
+// every request frame with the frame returned by answer, or not at all if it
+// returns nil.
+func fakeLogger(t *testing.T, answer func(request []byte) []byte) net.Listener {
+	t.Helper()
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Listen failed: %v", err)
+	}
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				defer conn.Close()
+				reader := newFrameReader(conn)
+				for {
+					request, err := reader.ReadFrame()
+					if err != nil {
+						return
+					}
+					if response := answer(request); response != nil {
+						conn.Write(response)
+					}
+				}
+			}()
+		}
+	}()
+	return listener
+}
+
+// serveGateway starts a Modbus TCP gateway on top of handler.
+func serveGateway(t *testing.T, handler *SolarmanClientHandler, unitIDs map[byte]byte) (*ModbusTCPGateway, string) {
+	t.Helper()
+	gateway := NewModbusTCPGateway(handler)
+	gateway.UnitIDs = unitIDs
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Listen failed: %v", err)
+	}
+	go gateway.Serve(listener)
+	return gateway, listener.Addr().String()
+}
+
+// modbusTCPClient creates a Modbus TCP client for a unit ID.
+func modbusTCPClient(t *testing.T, address string, unitID byte) modbus.Client {
+	t.Helper()
+	handler := modbus.NewTCPClientHandler(address)
+	handler.SlaveID = unitID
+	handler.Timeout = 2 * time.Second
+	t.Cleanup(func() { handler.Close() })
+	return modbus.NewClient(handler)
+}
+
+func TestModbusTCPGateway(t *testing.T) {
+	logger := fakeLogger(t, func(request []byte) []byte {
+		address := uint16(request[28])<<8 | uint16(request[29])
+		switch address {
+		case 0xFFFF:
+			return rtuResponseFrame(request, request[27]|0x80, []byte{modbus.ExceptionCodeIllegalDataAddress})
+		case 0xFFFE:
+			return nil
+		}
+		// Answer with the slave ID and the address.
+		return responseFrame(request, uint16(request[26]), address)
+	})
+	defer logger.Close()
+
+	handler := NewSolarmanClientHandler(logger.Addr().String(), 0x12345678)
+	handler.SlaveID = 0x07
+	handler.Timeout = 200 * time.Millisecond
+	handler.RetryPolicy = &BackoffRetryPolicy{MaxAttempts: 1}
+	gateway, address := serveGateway(t, handler, nil)
+	defer gateway.Close()
+
+	tests := []struct {
+		unitID   byte
+		address  uint16
+		expected []byte
+	}{
+		{1, 0x0100, []byte{0x00, 0x01, 0x01, 0x00}},
+		{3, 0x0200, []byte{0x00, 0x03, 0x02, 0x00}},
+		{0xFF, 0x0300, []byte{0x00, 0x07, 0x03, 0x00}},
+	}
+	for _, test := range tests {
+		results, err := modbusTCPClient(t, address, test.unitID).ReadHoldingRegisters(test.address, 2)
+		if err != nil {
+			t.Fatalf("Unit %d: ReadHoldingRegisters failed: %v", test.unitID, err)
+		}
+		if !bytes.Equal(results, test.expected) {
+			t.Errorf("Unit %d: expected %X, got %X", test.unitID, test.expected, results)
+		}
+	}
+
+	client := modbusTCPClient(t, address, 1)
+	_, err := client.ReadHoldingRegisters(0xFFFF, 2)
+	var modbusErr *modbus.Error
+	if !errors.As(err, &modbusErr) || modbusErr.ExceptionCode != modbus.ExceptionCodeIllegalDataAddress {
+		t.Errorf("Expected illegal data address exception, got %v", err)
+	}
+	_, err = client.ReadHoldingRegisters(0xFFFE, 2)
+	if !errors.As(err, &modbusErr) || modbusErr.ExceptionCode != modbus.ExceptionCodeGatewayTargetDeviceFailedToRespond {
+		t.Errorf("Expected gateway target device failed to respond exception, got %v", err)
+	}
+
+	mapped, address := serveGateway(t, handler, map[byte]byte{1: 2})
+	defer mapped.Close()
+	results, err := modbusTCPClient(t, address, 1).ReadHoldingRegisters(0x0100, 2)
+	if err != nil || !bytes.Equal(results, []byte{0x00, 0x02, 0x01, 0x00}) {
+		t.Errorf("Expected unit 1 to map to slave 2, got %X, %v", results, err)
+	}
+	_, err = modbusTCPClient(t, address, 3).ReadHoldingRegisters(0x0100, 2)
+	if !errors.As(err, &modbusErr) || modbusErr.ExceptionCode != modbus.ExceptionCodeGatewayPathUnavailable {
+		t.Errorf("Expected gateway path unavailable exception, got %v", err)
+	}
+}
+
+func TestModbusTCPGatewayConcurrent(t *testing.T) {
+	logger := fakeLogger(t, echoAddress)
+	defer logger.Close()
+
+	handler := NewSolarmanClientHandler(logger.Addr().String(), 0x12345678)
+	gateway, address := serveGateway(t, handler, nil)
+	defer gateway.Close()
+
+	errs := make(chan error, 4)
+	for i := range 4 {
+		client := modbusTCPClient(t, address, 1)
+		go func() {
+			for j := range 10 {
+				register := uint16(100*i + j)
+				results, err := client.ReadHoldingRegisters(register, 1)
+				if err == nil && !bytes.Equal(results, dataBlock(register)) {
+					err = errors.New("unexpected results")
+				}
+				if err != nil {
+					errs <- err
+					return
+				}
+			}
+			errs <- nil
+		}()
+	}
+	for range 4 {
+		if err := <-errs; err != nil {
+			t.Errorf("Client failed: %v", err)
+		}
+	}
+}
+
+func TestReadMBAP(t *testing.T) {
+	frame := []byte{0x00, 0x01, 0x00, 0x00, 0x00, 0x06, 0x01, 0x03, 0x00, 0x10, 0x00, 0x02}
+	header, pdu, err := readMBAP(bytes.NewReader(frame))
+	if err != nil {
+		t.Fatalf("readMBAP failed: %v", err)
+	}
+	if header[6] != 0x01 || pdu.FunctionCode != 0x03 || !bytes.Equal(pdu.Data, []byte{0x00, 0x10, 0x00, 0x02}) {
+		t.Errorf("Unexpected frame %X %+v", header, pdu)
+	}
+	response := mbapFrame(header, &modbus.ProtocolDataUnit{FunctionCode: 0x83, Data: []byte{0x02}})
+	if expected := []byte{0x00, 0x01, 0x00, 0x00, 0x00, 0x03, 0x01, 0x83, 0x02}; !bytes.Equal(response, expected) {
+		t.Errorf("Expected %X, got %X", expected, response)
+	}
+
+	frame[2] = 0x01
+	if _, _, err := readMBAP(bytes.NewReader(frame)); err == nil {
+		t.Errorf("Expected unsupported protocol ID to fail")
+	}
+	frame[2], frame[5] = 0x00, 0x08
+	if _, _, err := readMBAP(bytes.NewReader(frame)); err == nil {
+		t.Errorf("Expected truncated frame to fail")
+	}
+}
+
+func TestExceptionCode(t *testing.T) {
+	tests := []struct {
+		err      error
+		expected byte
+	}{
+		{fmt.Errorf("%w to %q: %w", errConnect, "logger", &net.OpError{Op: "dial", Err: syscall.ECONNREFUSED}), modbus.ExceptionCodeGatewayPathUnavailable},
+		{fmt.Errorf("%w to %q: %w", errConnect, "logger", errors.New("proxy unavailable")), modbus.ExceptionCodeGatewayPathUnavailable},
+		{fmt.Errorf("%w to %q: %w", errConnect, "logger", context.DeadlineExceeded), modbus.ExceptionCodeGatewayPathUnavailable},
+		{newFrameError(ErrChecksum, 1, 2, nil), modbus.ExceptionCodeServerDeviceFailure},
+		{os.ErrDeadlineExceeded, modbus.ExceptionCodeGatewayTargetDeviceFailedToRespond},
+		{&net.OpError{Op: "read", Net: "udp", Err: os.ErrDeadlineExceeded}, modbus.ExceptionCodeGatewayTargetDeviceFailedToRespond},
+		{context.DeadlineExceeded, modbus.ExceptionCodeGatewayTargetDeviceFailedToRespond},
+		{io.EOF, modbus.ExceptionCodeGatewayTargetDeviceFailedToRespond},
+		{&InverterError{}, modbus.ExceptionCodeGatewayTargetDeviceFailedToRespond},
+		{fmt.Errorf("%w: response from slave 2 to request for slave 1", errSlaveIDMismatch), modbus.ExceptionCodeGatewayTargetDeviceFailedToRespond},
+	}
+	for _, test := range tests {
+		if code := exceptionCode(test.err, DefaultRetryPolicy); code != test.expected {
+			t.Errorf("%v: expected 0x%02X, got 0x%02X", test.err, test.expected, code)
+		}
+	}
+}
+
+func TestModbusTCPGatewaySlaveIDMismatch(t *testing.T) {
+	logger := fakeLogger(t, func(request []byte) []byte {
+		request = bytes.Clone(request)
+		request[26]++
+		return echoAddress(request)
+	})
+	defer logger.Close()
+	handler := NewSolarmanClientHandler(logger.Addr().String(), 1234567891)
+	defer handler.Close()
+	gateway, address := serveGateway(t, handler, nil)
+	defer gateway.Close()
+
+	_, err := modbusTCPClient(t, address, 1).ReadHoldingRegisters(0x0100, 2)
+	var modbusErr *modbus.Error
+	if !errors.As(err, &modbusErr) || modbusErr.ExceptionCode != modbus.ExceptionCodeGatewayTargetDeviceFailedToRespond {
+		t.Errorf("Expected gateway target device failed to respond exception, got %v", err)
+	}
+}
+
+func TestModbusTCPGatewayDialerFailure(t *testing.T) {
+	handler := NewSolarmanClientHandler("logger", 1234567891)
+	handler.RetryPolicy = &BackoffRetryPolicy{}
+	handler.Dialer = DialerFunc(func(ctx context.Context, network, address string) (net.Conn, error) {
+		return nil, errors.New("proxy unavailable")
+	})
+	gateway, address := serveGateway(t, handler, nil)
+	defer gateway.Close()
+
+	_, err := modbusTCPClient(t, address, 1).ReadHoldingRegisters(0x0100, 2)
+	var modbusErr *modbus.Error
+	if !errors.As(err, &modbusErr) || modbusErr.ExceptionCode != modbus.ExceptionCodeGatewayPathUnavailable {
+		t.Errorf("Expected gateway path unavailable exception, got %v", err)
+	}
+}
@@ -0,0 +1,80 @@
+package gosolarman
+
+import (
+	"context"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"log/slog"
+	"strings"
+)
+
+// log writes a record to the StructuredLogger and the Logger of the
+// transporter. Nothing is written if neither is configured.
+//
+// Parameters:
+//   - ctx: The context of the operation being logged.
+//   - level: The level of the record.
+//   - msg: The message of the record.
+//   - attrs: The attributes of the record. The address is added automatically.
+func (mb *solarmanTransporter) log(ctx context.Context, level slog.Level, msg string, attrs ...slog.Attr) {
+	if !mb.logEnabled(ctx, level) {
+		return
+	}
+	attrs = append([]slog.Attr{slog.String("address", mb.Address)}, attrs...)
+	if mb.StructuredLogger != nil {
+		mb.StructuredLogger.LogAttrs(ctx, level, msg, attrs...)
+	}
+	if mb.Logger != nil {
+		var line strings.Builder
+		line.WriteString(level.String())
+		line.WriteByte(' ')
+		line.WriteString(msg)
+		for _, attr := range attrs {
+			line.WriteByte(' ')
+			line.WriteString(attr.String())
+		}
+		mb.Logger.Printf("%s\n", line.String())
+	}
+}
+
+// logEnabled reports whether a record of the given level would be written.
+// It is used to avoid formatting frames that are not logged.
+//
+// Parameters:
+//   - ctx: The context of the operation being logged.
+//   - level: The level of the record.
+//
+// Returns:
+//   - true if a logger accepts the record.
+func (mb *solarmanTransporter) logEnabled(ctx context.Context, level slog.Level) bool {
+	return mb.Logger != nil || (mb.StructuredLogger != nil && mb.StructuredLogger.Enabled(ctx, level))
+}
+
+// frameAttrs returns the log attributes describing a frame: the logger
+// serial, control code and sequence number from the header, the slave ID and
+// function code of request frames, and the frame itself in hex.
+//
+// Parameters:
+//   - frame: The raw frame.
+//
+// Returns:
+//   - The attributes of the frame.
+func frameAttrs(frame []byte) []slog.Attr {
+	attrs := make([]slog.Attr, 0, 6)
+	if len(frame) >= headerLength {
+		controlCode := binary.LittleEndian.Uint16(frame[3:5])
+		attrs = append(attrs,
+			slog.Uint64("logger_serial", uint64(binary.LittleEndian.Uint32(frame[7:11]))),
+			slog.String("control_code", fmt.Sprintf("0x%04X", controlCode)),
+			slog.Int("sequence", int(binary.LittleEndian.Uint16(frame[5:7]))),
+		)
+		if controlCode == ControlCodeRequest && len(frame) >= headerLength+requestPayloadLength+2 {
+			attrs = append(attrs,
+				slog.Int("slave_id", int(frame[headerLength+requestPayloadLength])),
+				slog.Int("function_code", int(frame[headerLength+requestPayloadLength+1])),
+			)
+		}
+	}
+	return append(attrs, slog.String("frame", hex.EncodeToString(frame)))
+}
@@ -0,0 +1,56 @@
+package gosolarman
+
+import (
+	"bytes"
+	"context"
+	"encoding/hex"
+	"log/slog"
+	"strings"
+	"testing"
+)
+
+func TestSendStructuredLogging(t *testing.T) {
+	mock := &mockConn{}
+	var records bytes.Buffer
+	handler := &solarmanTransporter{
+		Address:          "192.168.1.1:8899",
+		conn:             mock,
+		StructuredLogger: slog.New(slog.NewTextHandler(&records, &slog.HandlerOptions{Level: slog.LevelDebug})),
+	}
+
+	aduRequest, _ := hex.DecodeString("a5170010452a00d302964902000000000000000000000000000001030271000295a80215")
+	aduResponse, _ := hex.DecodeString("a517001015012ad302964902013412000058020000f3a01265010304000102716ab76515")
+	aduResponse[5], aduResponse[6] = aduRequest[5], aduRequest[6]
+	aduResponse[len(aduResponse)-2] = CheckSum(aduResponse[1 : len(aduResponse)-2])
+	mock.readBuffer.Write(aduResponse)
+
+	if _, err := handler.SendContext(context.Background(), aduRequest); err != nil {
+		t.Fatalf("SendContext failed: %v", err)
+	}
+
+	output := records.String()
+	for _, expected := range []string{
+		`msg="sent frame" address=192.168.1.1:8899 logger_serial=1234567891 control_code=0x4510 sequence=42 slave_id=1 function_code=3 frame=a5170010452a`,
+		`msg="received frame" address=192.168.1.1:8899 logger_serial=1234567891 control_code=0x1510`,
+		"latency=",
+	} {
+		if !strings.Contains(output, expected) {
+			t.Errorf("Expected log output to contain %q, got:\n%s", expected, output)
+		}
+	}
+}
+
+func TestVerifyLogsFailure(t *testing.T) {
+	var records bytes.Buffer
+	handler := NewSolarmanClientHandler("192.168.1.1:8899", 0x12345678)
+	handler.StructuredLogger = slog.New(slog.NewTextHandler(&records, nil))
+
+	aduRequest := []byte{0xA5, 0x0E, 0x00, 0x10, 0x45, 0x01, 0x00, 0x4F, 0xAD, 0x6D, 0xA5}
+	aduResponse := []byte{0xA5, 0x0E, 0x00, 0x10, 0x15, 0x01, 0x00, 0x4F, 0xAD, 0x6D, 0xA5, 0x00, 0x15}
+	if err := handler.Verify(aduRequest, aduResponse); err == nil {
+		t.Fatalf("Expected checksum mismatch")
+	}
+	if output := records.String(); !strings.Contains(output, `level=WARN msg="verify failed"`) || !strings.Contains(output, "checksum mismatch") {
+		t.Errorf("Expected verify failure to be logged, got:\n%s", output)
+	}
+}
@@ -0,0 +1,79 @@
+package gosolarman
+
+import (
+	"errors"
+	"time"
+)
+
+// OutcomeSuccess is the outcome reported to Metrics for successful requests.
+// Failed requests report the name of their ErrorClass.
+const OutcomeSuccess = "success"
+
+// Metrics receives measurements of the exchanges of a handler.
+// Implementations must be safe for concurrent use.
+type Metrics interface {
+	// ObserveRequest is called once per request sent by the transporter, after
+	// all retries, with the Modbus function code, the outcome and the latency.
+	// Responses are verified first, so an invalid response of a handler is
+	// reported with the outcome "protocol".
+	ObserveRequest(functionCode byte, outcome string, latency time.Duration)
+
+	// ObserveReconnect is called whenever a new connection replaces a previous one.
+	ObserveReconnect()
+
+	// ObserveFrameError is called for every response that fails verification
+	// or decoding, with the kind of failure (e.g., "checksum", "crc", "sequence").
+	ObserveFrameError(kind string)
+
+	// ObserveUnsolicitedFrame is called for every frame pushed by the data
+	// logging stick while waiting for a response.
+	ObserveUnsolicitedFrame(controlCode uint16)
+}
+
+// frameErrorKinds maps the sentinel errors to the kinds reported to Metrics.
+var frameErrorKinds = []struct {
+	err  error
+	kind string
+}{
+	{ErrShortFrame, "short_frame"},
+	{ErrBadStartByte, "start_byte"},
+	{ErrBadEndByte, "end_byte"},
+	{ErrLengthMismatch, "length"},
+	{ErrChecksum, "checksum"},
+	{ErrCRC, "crc"},
+	{ErrSequenceMismatch, "sequence"},
+	{ErrControlCode, "control_code"},
+	{ErrLoggerSerialMismatch, "logger_serial"},
+	{ErrInverterUnreachable, "inverter_unreachable"},
+}
+
+// FrameErrorKind returns the kind of a verification or decoding error as
+// reported to Metrics.
+//
+// Parameters:
+//   - err: The error returned by Verify or Decode.
+//
+// Returns:
+//   - The kind of the error, or "other" if it wraps none of the sentinel errors.
+func FrameErrorKind(err error) string {
+	for _, k := range frameErrorKinds {
+		if errors.Is(err, k.err) {
+			return k.kind
+		}
+	}
+	return "other"
+}
+
+// requestFunctionCode returns the Modbus function code of a request frame.
+//
+// Parameters:
+//   - frame: The request frame.
+//
+// Returns:
+//   - The function code, or 0 if the frame is too short.
+func requestFunctionCode(frame []byte) byte {
+	if len(frame) < headerLength+requestPayloadLength+2 {
+		return 0
+	}
+	return frame[headerLength+requestPayloadLength+1]
+}
@@ -0,0 +1,173 @@
+package gosolarman
+
+import (
+	"context"
+	"errors"
+	"net"
+	"sync"
+	"testing"
+	"time"
+)
+
+// recordingMetrics is a Metrics implementation that records all observations.
+type recordingMetrics struct {
+	mu          sync.Mutex
+	requests    []string
+	reconnects  int
+	frameErrors []string
+	unsolicited []uint16
+}
+
+func (m *recordingMetrics) ObserveRequest(functionCode byte, outcome string, latency time.Duration) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.requests = append(m.requests, outcome)
+}
+
+func (m *recordingMetrics) ObserveReconnect() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.reconnects++
+}
+
+func (m *recordingMetrics) ObserveFrameError(kind string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.frameErrors = append(m.frameErrors, kind)
+}
+
+func (m *recordingMetrics) ObserveUnsolicitedFrame(controlCode uint16) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.unsolicited = append(m.unsolicited, controlCode)
+}
+
+func TestMetrics(t *testing.T) {
+	metrics := &recordingMetrics{}
+	mock := &mockConn{}
+	handler := NewSolarmanClientHandler("192.168.1.1:8899", 0x12345678)
+	handler.conn = mock
+	handler.Metrics = metrics
+
+	aduRequest := []byte{0xA5, 0x0E, 0x00, 0x10, 0x45, 0x01, 0x00, 0x4F, 0xAD, 0x6D, 0xA5}
+	mock.readBuffer.Write([]byte{0xA5, 0x00, 0x00, 0x10, 0x47, 0x05, 0x00, 0x4F, 0xAD, 0x6D, 0xA5, 0x00, 0x15})
+	mock.readBuffer.Write([]byte{0xA5, 0x00, 0x00, 0x10, 0x15, 0x01, 0x00, 0x4F, 0xAD, 0x6D, 0xA5, 0x00, 0x15})
+
+	if _, err := handler.Send(aduRequest); !errors.Is(err, ErrChecksum) {
+		t.Fatalf("Expected checksum mismatch, got %v", err)
+	}
+
+	if len(metrics.requests) != 1 || metrics.requests[0] != ErrorClassProtocol.String() {
+		t.Errorf("Expected one protocol failure, got %v", metrics.requests)
+	}
+	if len(metrics.unsolicited) != 1 || metrics.unsolicited[0] != ControlCodeHeartbeat {
+		t.Errorf("Expected one heartbeat, got %v", metrics.unsolicited)
+	}
+	if len(metrics.frameErrors) != 1 || metrics.frameErrors[0] != "checksum" {
+		t.Errorf("Expected one checksum error, got %v", metrics.frameErrors)
+	}
+}
+
+// badCRC returns a copy of a response frame with a wrong CRC and a valid checksum.
+func badCRC(response []byte) []byte {
+	response = append([]byte(nil), response...)
+	response[len(response)-4]++
+	response[len(response)-2] = CheckSum(response[1 : len(response)-2])
+	return response
+}
+
+func TestMetricsProtocolOutcome(t *testing.T) {
+	client, server := newPipeClient()
+	defer server.Close()
+	metrics := &recordingMetrics{}
+	client.handler.Metrics = metrics
+	go func() {
+		request, err := newFrameReader(server).ReadFrame()
+		if err != nil {
+			return
+		}
+		server.Write(badCRC(responseFrame(request, 0x0001)))
+	}()
+
+	if _, err := client.ReadHoldingRegistersContext(context.Background(), 0, 1); !errors.Is(err, ErrCRC) {
+		t.Fatalf("Expected ErrCRC, got %v", err)
+	}
+	if len(metrics.requests) != 1 || metrics.requests[0] != ErrorClassProtocol.String() {
+		t.Errorf("Expected one protocol failure, got %v", metrics.requests)
+	}
+	if len(metrics.frameErrors) != 1 || metrics.frameErrors[0] != "crc" {
+		t.Errorf("Expected one CRC error, got %v", metrics.frameErrors)
+	}
+}
+
+func TestMetricsPipelinedProtocolOutcome(t *testing.T) {
+	client, server := newPipeClient()
+	defer server.Close()
+	metrics := &recordingMetrics{}
+	client.handler.Metrics = metrics
+	client.handler.PipelineWindow = 2
+	go func() {
+		reader := newFrameReader(server)
+		var requests [][]byte
+		for range 2 {
+			request, err := reader.ReadFrame()
+			if err != nil {
+				return
+			}
+			requests = append(requests, request)
+		}
+		server.Write(echoAddress(requests[0]))
+		server.Write(badCRC(echoAddress(requests[1])))
+		// The request with the invalid response is sent again on its own.
+		if request, err := reader.ReadFrame(); err == nil {
+			server.Write(badCRC(echoAddress(request)))
+		}
+	}()
+
+	futures := client.SendPipelinedContext(context.Background(), readRequests(10, 20))
+	if _, err := futures[0].Wait(context.Background()); err != nil {
+		t.Errorf("Expected the first request to succeed, got %v", err)
+	}
+	if _, err := futures[1].Wait(context.Background()); !errors.Is(err, ErrCRC) {
+		t.Errorf("Expected ErrCRC for the second request, got %v", err)
+	}
+
+	metrics.mu.Lock()
+	defer metrics.mu.Unlock()
+	expected := []string{OutcomeSuccess, ErrorClassProtocol.String()}
+	if len(metrics.requests) != 2 || metrics.requests[0] != expected[0] || metrics.requests[1] != expected[1] {
+		t.Errorf("Expected outcomes %v, got %v", expected, metrics.requests)
+	}
+}
+
+func TestMetricsReconnect(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Listen failed: %v", err)
+	}
+	defer listener.Close()
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			conn.Close()
+		}
+	}()
+
+	metrics := &recordingMetrics{}
+	handler := NewSolarmanClientHandler(listener.Addr().String(), 0x12345678)
+	handler.Metrics = metrics
+	handler.RetryPolicy = &BackoffRetryPolicy{MaxAttempts: 3, Reconnect: true}
+
+	if _, err := handler.Send([]byte{0xA5, 0x0E, 0x00, 0x10, 0x45, 0x01, 0x00, 0x4F, 0xAD, 0x6D, 0xA5}); err == nil {
+		t.Fatalf("Expected Send to fail")
+	}
+	if metrics.reconnects != 2 {
+		t.Errorf("Expected 2 reconnects, got %d", metrics.reconnects)
+	}
+	if len(metrics.requests) != 1 || metrics.requests[0] != ErrorClassTransient.String() {
+		t.Errorf("Expected one transient failure, got %v", metrics.requests)
+	}
+}
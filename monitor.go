@@ -0,0 +1,179 @@
+package gosolarman
+
+import (
+	"sync"
+	"time"
+)
+
+// DefaultRestartTolerance is the RestartTolerance used by monitors that do not set one.
+const DefaultRestartTolerance = 30 * time.Second
+
+// MonitorEventType denotes the kind of a MonitorEvent.
+type MonitorEventType int
+
+const (
+	// MonitorEventRestart is emitted when the data logging stick restarted
+	// between two responses.
+	MonitorEventRestart MonitorEventType = iota
+
+	// MonitorEventDrift is emitted when the clock drift of the data logging
+	// stick exceeds the DriftThreshold.
+	MonitorEventDrift
+
+	// MonitorEventStatus is emitted when the status reported by the data
+	// logging stick changes.
+	MonitorEventStatus
+)
+
+// String returns the name of the event type.
+func (t MonitorEventType) String() string {
+	switch t {
+	case MonitorEventRestart:
+		return "restart"
+	case MonitorEventDrift:
+		return "drift"
+	case MonitorEventStatus:
+		return "status"
+	default:
+		return "unknown"
+	}
+}
+
+// MonitorEvent describes a change in the state of the data logging stick.
+type MonitorEvent struct {
+	Type     MonitorEventType // Kind of the event.
+	Info     FrameInfo        // Metadata of the response that triggered the event.
+	Previous FrameInfo        // Metadata of the preceding response.
+	Drift    time.Duration    // Clock drift of the data logging stick at the time of the event.
+}
+
+// MonitorStats holds the counters of a Monitor.
+type MonitorStats struct {
+	Responses     uint64        // Number of observed responses.
+	Restarts      uint64        // Number of detected restarts.
+	StatusChanges uint64        // Number of observed status changes.
+	Drift         time.Duration // Last clock drift (logger clock minus local clock).
+	Last          FrameInfo     // Metadata of the last observed response.
+}
+
+// Monitor watches the logger metadata of the responses of a handler to
+// detect restarts, clock drift and status changes of the data logging stick.
+// It is safe for concurrent use.
+type Monitor struct {
+	DriftThreshold   time.Duration            // Absolute drift above which MonitorEventDrift is emitted (0 to disable).
+	RestartTolerance time.Duration            // Allowed lag of the logger uptime behind the local clock (DefaultRestartTolerance if 0).
+	OnEvent          func(event MonitorEvent) // Called for every event, with the monitor unlocked.
+
+	mu       sync.Mutex   // Mutex for thread-safe access to the state.
+	stats    MonitorStats // Counters and last observed metadata.
+	drifting bool         // Whether the drift exceeded DriftThreshold at the last response.
+}
+
+// NewMonitor creates a new monitor.
+//
+// Parameters:
+//   - DriftThreshold: The absolute drift above which MonitorEventDrift is emitted (0 to disable).
+//
+// Returns:
+//   - A pointer to the created Monitor.
+func NewMonitor(DriftThreshold time.Duration) *Monitor {
+	return &Monitor{
+		DriftThreshold:   DriftThreshold,
+		RestartTolerance: DefaultRestartTolerance,
+	}
+}
+
+// Attach makes the monitor observe every response decoded by handler. An
+// existing OnFrameInfo callback of the handler is still called.
+// Attach must be called before the handler is used.
+//
+// Parameters:
+//   - handler: The Solarman client handler to monitor.
+func (m *Monitor) Attach(handler *SolarmanClientHandler) {
+	next := handler.OnFrameInfo
+	handler.OnFrameInfo = func(info FrameInfo) {
+		m.Observe(info)
+		if next != nil {
+			next(info)
+		}
+	}
+}
+
+// Observe processes the logger metadata of a response.
+//
+// Parameters:
+//   - info: The metadata of the response.
+func (m *Monitor) Observe(info FrameInfo) {
+	var events []MonitorEvent
+
+	m.mu.Lock()
+	previous := m.stats.Last
+	first := m.stats.Responses == 0
+	drift, hasDrift := info.Drift()
+
+	m.stats.Responses++
+	m.stats.Last = info
+	if hasDrift {
+		m.stats.Drift = drift
+	}
+	if !first && m.restarted(previous, info) {
+		m.stats.Restarts++
+		events = append(events, MonitorEvent{Type: MonitorEventRestart, Info: info, Previous: previous, Drift: drift})
+	}
+	if !first && info.Status != previous.Status {
+		m.stats.StatusChanges++
+		events = append(events, MonitorEvent{Type: MonitorEventStatus, Info: info, Previous: previous, Drift: drift})
+	}
+	if hasDrift && m.DriftThreshold > 0 {
+		drifting := drift > m.DriftThreshold || drift < -m.DriftThreshold
+		if drifting && !m.drifting {
+			events = append(events, MonitorEvent{Type: MonitorEventDrift, Info: info, Previous: previous, Drift: drift})
+		}
+		m.drifting = drifting
+	}
+	m.mu.Unlock()
+
+	if m.OnEvent != nil {
+		for _, event := range events {
+			m.OnEvent(event)
+		}
+	}
+}
+
+// Stats returns a snapshot of the counters of the monitor.
+//
+// Returns:
+//   - The current counters.
+func (m *Monitor) Stats() MonitorStats {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return m.stats
+}
+
+// restarted reports whether the data logging stick restarted between two
+// responses, i.e. its uptime went backwards or advanced less than the local
+// clock by more than RestartTolerance. Sticks that report a PowerOnTime of 0
+// do not report their uptime, so no restart is detected for them.
+//
+// Parameters:
+//   - previous: The metadata of the preceding response.
+//   - info: The metadata of the current response.
+//
+// Returns:
+//   - true if a restart was detected.
+func (m *Monitor) restarted(previous, info FrameInfo) bool {
+	if info.PowerOnTime == 0 || previous.PowerOnTime == 0 {
+		return false
+	}
+	if info.PowerOnTime < previous.PowerOnTime {
+		return true
+	}
+	tolerance := m.RestartTolerance
+	if tolerance == 0 {
+		tolerance = DefaultRestartTolerance
+	}
+	elapsed := info.ReceivedAt.Sub(previous.ReceivedAt)
+	uptime := time.Duration(info.PowerOnTime-previous.PowerOnTime) * time.Second
+	return elapsed-uptime > tolerance
+}
@@ -0,0 +1,88 @@
+package gosolarman
+
+import (
+	"testing"
+	"time"
+)
+
+func TestMonitor(t *testing.T) {
+	monitor := NewMonitor(time.Minute)
+	var events []MonitorEvent
+	monitor.OnEvent = func(event MonitorEvent) {
+		events = append(events, event)
+	}
+
+	start := time.Unix(1700000000, 0)
+	info := func(elapsed time.Duration, powerOnTime uint32, offset int64, status byte) FrameInfo {
+		now := start.Add(elapsed)
+		return FrameInfo{
+			ReceivedAt:  now,
+			Status:      status,
+			PowerOnTime: powerOnTime,
+			OffsetTime:  uint32(now.Unix() + offset - int64(powerOnTime)),
+		}
+	}
+
+	monitor.Observe(info(0, 1000, 0, 0x01))
+	monitor.Observe(info(time.Minute, 1060, 5, 0x01))
+	monitor.Observe(info(2*time.Minute, 10, 5, 0x01))            // Uptime went backwards
+	monitor.Observe(info(time.Hour, 600, 5, 0x01))               // Restarted between polls
+	monitor.Observe(info(time.Hour+time.Minute, 660, 120, 0x02)) // Clock jumped ahead, status changed
+
+	expected := []MonitorEventType{MonitorEventRestart, MonitorEventRestart, MonitorEventStatus, MonitorEventDrift}
+	if len(events) != len(expected) {
+		t.Fatalf("Expected events %v, got %v", expected, events)
+	}
+	for i, event := range events {
+		if event.Type != expected[i] {
+			t.Errorf("Expected event %d to be %s, got %s", i, expected[i], event.Type)
+		}
+	}
+	if events[3].Drift != 2*time.Minute {
+		t.Errorf("Expected drift 2m, got %v", events[3].Drift)
+	}
+
+	stats := monitor.Stats()
+	if stats.Responses != 5 || stats.Restarts != 2 || stats.StatusChanges != 1 || stats.Drift != 2*time.Minute {
+		t.Errorf("Unexpected stats %+v", stats)
+	}
+}
+
+func TestMonitorWithoutUptime(t *testing.T) {
+	monitor := NewMonitor(0)
+	var events []MonitorEvent
+	monitor.OnEvent = func(event MonitorEvent) {
+		events = append(events, event)
+	}
+
+	start := time.Unix(1700000000, 0)
+	for i := range 3 {
+		monitor.Observe(FrameInfo{ReceivedAt: start.Add(time.Duration(i) * time.Hour), Status: 0x01})
+	}
+
+	if len(events) != 0 {
+		t.Errorf("Expected no events for a stick without uptime, got %v", events)
+	}
+	if stats := monitor.Stats(); stats.Restarts != 0 {
+		t.Errorf("Expected no restarts, got %d", stats.Restarts)
+	}
+}
+
+func TestMonitorAttach(t *testing.T) {
+	handler := NewSolarmanClientHandler("192.168.1.1:8899", 0x12345678)
+	called := false
+	handler.OnFrameInfo = func(info FrameInfo) {
+		called = true
+	}
+
+	monitor := NewMonitor(0)
+	monitor.Attach(handler)
+	handler.OnFrameInfo(FrameInfo{ReceivedAt: time.Now(), PowerOnTime: 10})
+
+	if !called {
+		t.Errorf("Expected the previous OnFrameInfo to be called")
+	}
+	if stats := monitor.Stats(); stats.Responses != 1 {
+		t.Errorf("Expected 1 response, got %d", stats.Responses)
+	}
+}
@@ -0,0 +1,114 @@
+// Package otelsolarman provides an OpenTelemetry tracer for gosolarman handlers.
+package otelsolarman
+
+import (
+	"context"
+	"encoding/binary"
+
+	"github.com/tlmnb/gosolarman"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// instrumentationName is the name of the OpenTelemetry tracer.
+const instrumentationName = "github.com/tlmnb/gosolarman/otelsolarman"
+
+// Tracer implements gosolarman.Tracer with OpenTelemetry spans.
+type Tracer struct {
+	tracer trace.Tracer
+}
+
+// NewTracer creates a new tracer.
+//
+// Parameters:
+//   - provider: The tracer provider to create spans with (the global provider if nil).
+//
+// Returns:
+//   - A pointer to the created Tracer.
+func NewTracer(provider trace.TracerProvider) *Tracer {
+	if provider == nil {
+		provider = otel.GetTracerProvider()
+	}
+	return &Tracer{
+		tracer: provider.Tracer(instrumentationName),
+	}
+}
+
+// Attach sets the Tracer of handler. Attach must be called before the handler is used.
+//
+// Parameters:
+//   - handler: The Solarman client handler to trace.
+func (t *Tracer) Attach(handler *gosolarman.SolarmanClientHandler) {
+	handler.Tracer = t
+}
+
+// StartSpan implements gosolarman.Tracer.
+func (t *Tracer) StartSpan(ctx context.Context, name string) (context.Context, gosolarman.Span) {
+	kind := trace.SpanKindInternal
+	if name == gosolarman.SpanRequest || name == gosolarman.SpanSend || name == gosolarman.SpanPipeline {
+		kind = trace.SpanKindClient
+	}
+	ctx, s := t.tracer.Start(ctx, name, trace.WithSpanKind(kind))
+	return ctx, &span{s}
+}
+
+// span implements gosolarman.Span on top of an OpenTelemetry span.
+type span struct {
+	trace.Span
+}
+
+// SetRequest implements gosolarman.Span.
+func (s *span) SetRequest(frame []byte) {
+	request, err := gosolarman.ParseRequest(frame)
+	if err != nil {
+		return
+	}
+	pdu := request.Payload.ModbusRTUFrame
+	s.SetAttributes(
+		attribute.Int("solarman.sequence", int(request.Header.SequenceNumber)),
+		attribute.Int64("solarman.logger_serial", int64(request.Header.LoggerSerialNumber)),
+		attribute.Int("modbus.slave_id", int(request.Payload.SlaveID)),
+		attribute.Int("modbus.function_code", int(pdu.FunctionCode)),
+	)
+	if len(pdu.Data) >= 2 {
+		s.SetAttributes(attribute.Int("modbus.address", int(binary.BigEndian.Uint16(pdu.Data))))
+	}
+	if len(pdu.Data) >= 4 && hasQuantity(pdu.FunctionCode) {
+		s.SetAttributes(attribute.Int("modbus.quantity", int(binary.BigEndian.Uint16(pdu.Data[2:]))))
+	}
+}
+
+// SetAttempts implements gosolarman.Span.
+func (s *span) SetAttempts(attempts int) {
+	s.SetAttributes(
+		attribute.Int("solarman.attempts", attempts),
+		attribute.Int("solarman.retries", max(attempts-1, 0)),
+	)
+}
+
+// End implements gosolarman.Span.
+func (s *span) End(err error) {
+	if err != nil {
+		s.RecordError(err)
+		s.SetStatus(codes.Error, err.Error())
+	}
+	s.Span.End()
+}
+
+// hasQuantity reports whether the request data of a function code continues
+// with a quantity after the address.
+//
+// Parameters:
+//   - functionCode: The Modbus function code.
+//
+// Returns:
+//   - true for read functions and multiple writes.
+func hasQuantity(functionCode byte) bool {
+	switch functionCode {
+	case 0x01, 0x02, 0x03, 0x04, 0x0F, 0x10, 0x17:
+		return true
+	}
+	return false
+}
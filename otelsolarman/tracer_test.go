@@ -0,0 +1,196 @@
+package otelsolarman
+
+import (
+	"context"
+	"io"
+	"net"
+	"testing"
+
+	"github.com/grid-x/modbus"
+	"github.com/tlmnb/gosolarman"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+// serve answers every request on listener with a response carrying registers.
+func serve(t *testing.T, listener net.Listener, registers []byte) {
+	conn, err := listener.Accept()
+	if err != nil {
+		return
+	}
+	defer conn.Close()
+	buffer := make([]byte, 256)
+	for {
+		n, err := conn.Read(buffer)
+		if err != nil {
+			if err != io.EOF {
+				t.Logf("Read failed: %v", err)
+			}
+			return
+		}
+		request, err := gosolarman.ParseRequest(buffer[:n])
+		if err != nil {
+			t.Errorf("ParseRequest failed: %v", err)
+			return
+		}
+		response := &gosolarman.Response{
+			Header: &gosolarman.Header{
+				ControlCode:        gosolarman.ControlCodeResponse,
+				SequenceNumber:     request.Header.SequenceNumber,
+				LoggerSerialNumber: request.Header.LoggerSerialNumber,
+			},
+			Payload: &gosolarman.ResponsePayload{
+				FrameType: gosolarman.FrameType,
+				Status:    0x01,
+				SlaveID:   request.Payload.SlaveID,
+				ModbusRTUFrame: modbus.ProtocolDataUnit{
+					FunctionCode: request.Payload.ModbusRTUFrame.FunctionCode,
+					Data:         append([]byte{byte(len(registers))}, registers...),
+				},
+			},
+		}
+		frame, err := response.Marshal()
+		if err != nil {
+			t.Errorf("Marshal failed: %v", err)
+			return
+		}
+		if _, err := conn.Write(frame); err != nil {
+			return
+		}
+	}
+}
+
+func TestTracer(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Listen failed: %v", err)
+	}
+	defer listener.Close()
+	go serve(t, listener, []byte{0x00, 0x2A})
+
+	exporter := tracetest.NewInMemoryExporter()
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSyncer(exporter))
+
+	handler := gosolarman.NewSolarmanClientHandler(listener.Addr().String(), 0x12345678)
+	handler.SetSlave(1)
+	NewTracer(provider).Attach(handler)
+	defer handler.Close()
+
+	client := gosolarman.NewContextClient(handler)
+	if _, err := client.ReadHoldingRegistersContext(context.Background(), 0x0100, 1); err != nil {
+		t.Fatalf("ReadHoldingRegistersContext failed: %v", err)
+	}
+
+	spans := exporter.GetSpans()
+	byName := make(map[string]tracetest.SpanStub)
+	for _, span := range spans {
+		byName[span.Name] = span
+	}
+	for _, name := range []string{gosolarman.SpanRequest, gosolarman.SpanSend, gosolarman.SpanDial, gosolarman.SpanWrite, gosolarman.SpanRead, gosolarman.SpanVerify, gosolarman.SpanDecode} {
+		if _, ok := byName[name]; !ok {
+			t.Errorf("Missing span %s in %d spans", name, len(spans))
+		}
+	}
+
+	request := byName[gosolarman.SpanRequest]
+	if parent := byName[gosolarman.SpanSend].Parent.SpanID(); parent != request.SpanContext.SpanID() {
+		t.Errorf("Expected %s to be a child of %s", gosolarman.SpanSend, gosolarman.SpanRequest)
+	}
+	send := byName[gosolarman.SpanSend]
+	for _, name := range []string{gosolarman.SpanDial, gosolarman.SpanWrite, gosolarman.SpanRead, gosolarman.SpanVerify, gosolarman.SpanDecode} {
+		if parent := byName[name].Parent.SpanID(); parent != send.SpanContext.SpanID() {
+			t.Errorf("Expected %s to be a child of %s", name, gosolarman.SpanSend)
+		}
+	}
+
+	attributes := make(map[attribute.Key]attribute.Value)
+	for _, kv := range request.Attributes {
+		attributes[kv.Key] = kv.Value
+	}
+	expected := map[attribute.Key]int64{
+		"solarman.logger_serial": 0x12345678,
+		"modbus.slave_id":        1,
+		"modbus.function_code":   0x03,
+		"modbus.address":         0x0100,
+		"modbus.quantity":        1,
+	}
+	for key, value := range expected {
+		if got := attributes[key].AsInt64(); got != value {
+			t.Errorf("Expected %s = %d, got %d", key, value, got)
+		}
+	}
+	if got := send.Attributes; !hasAttribute(got, attribute.Int("solarman.attempts", 1)) {
+		t.Errorf("Expected one attempt, got %v", got)
+	}
+	if request.Status.Code == codes.Error {
+		t.Errorf("Expected successful request span, got %v", request.Status)
+	}
+}
+
+func TestTracerModbusClient(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Listen failed: %v", err)
+	}
+	defer listener.Close()
+	go serve(t, listener, []byte{0x00, 0x2A})
+
+	exporter := tracetest.NewInMemoryExporter()
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSyncer(exporter))
+
+	handler := gosolarman.NewSolarmanClientHandler(listener.Addr().String(), 0x12345678)
+	handler.SetSlave(1)
+	NewTracer(provider).Attach(handler)
+	defer handler.Close()
+
+	if _, err := modbus.NewClient(handler).ReadHoldingRegisters(0x0100, 1); err != nil {
+		t.Fatalf("ReadHoldingRegisters failed: %v", err)
+	}
+
+	byName := make(map[string]tracetest.SpanStub)
+	for _, span := range exporter.GetSpans() {
+		byName[span.Name] = span
+	}
+	send, ok := byName[gosolarman.SpanSend]
+	if !ok {
+		t.Fatalf("Missing span %s", gosolarman.SpanSend)
+	}
+	for _, name := range []string{gosolarman.SpanVerify, gosolarman.SpanDecode} {
+		span, ok := byName[name]
+		if !ok {
+			t.Errorf("Missing span %s", name)
+		} else if span.Parent.SpanID() != send.SpanContext.SpanID() {
+			t.Errorf("Expected %s to be a child of %s", name, gosolarman.SpanSend)
+		}
+	}
+}
+
+func TestTracerError(t *testing.T) {
+	exporter := tracetest.NewInMemoryExporter()
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSyncer(exporter))
+
+	_, span := NewTracer(provider).StartSpan(context.Background(), gosolarman.SpanSend)
+	span.End(gosolarman.ErrChecksum)
+
+	spans := exporter.GetSpans()
+	if len(spans) != 1 {
+		t.Fatalf("Expected 1 span, got %d", len(spans))
+	}
+	if spans[0].Status.Code != codes.Error || spans[0].Status.Description != gosolarman.ErrChecksum.Error() {
+		t.Errorf("Expected error status, got %v", spans[0].Status)
+	}
+	if len(spans[0].Events) != 1 {
+		t.Errorf("Expected the error to be recorded, got %v", spans[0].Events)
+	}
+}
+
+func hasAttribute(attributes []attribute.KeyValue, kv attribute.KeyValue) bool {
+	for _, attr := range attributes {
+		if attr == kv {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,339 @@
+package pcapsolarman
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math/bits"
+	"time"
+)
+
+const (
+	// pcapMagicMicroseconds is the magic number of pcap files with microsecond timestamps.
+	pcapMagicMicroseconds = 0xA1B2C3D4
+
+	// pcapMagicNanoseconds is the magic number of pcap files with nanosecond timestamps.
+	pcapMagicNanoseconds = 0xA1B23C4D
+
+	// pcapngSectionHeader is the block type of a pcapng section header block.
+	pcapngSectionHeader = 0x0A0D0D0A
+
+	// pcapngByteOrderMagic is the byte order magic of a pcapng section header block.
+	pcapngByteOrderMagic = 0x1A2B3C4D
+
+	// pcapngInterfaceDescription is the block type of a pcapng interface description block.
+	pcapngInterfaceDescription = 0x00000001
+
+	// pcapngPacket is the block type of an obsolete pcapng packet block.
+	pcapngPacket = 0x00000002
+
+	// pcapngSimplePacket is the block type of a pcapng simple packet block.
+	pcapngSimplePacket = 0x00000003
+
+	// pcapngEnhancedPacket is the block type of a pcapng enhanced packet block.
+	pcapngEnhancedPacket = 0x00000006
+
+	// pcapngOptionTimestampResolution is the option code of the timestamp
+	// resolution of an interface.
+	pcapngOptionTimestampResolution = 9
+
+	// maxCaptureLength is the largest packet or block accepted from a capture.
+	maxCaptureLength = 1 << 24
+)
+
+// ErrFormat is returned when a capture is neither a pcap nor a pcapng file,
+// or when it is malformed.
+var ErrFormat = errors.New("invalid capture format")
+
+// packet is a captured link layer packet.
+type packet struct {
+	time     time.Time // Capture time, or the zero time if unknown.
+	linkType uint16    // Link layer type of the capture interface.
+	data     []byte    // Captured bytes, starting with the link layer header.
+}
+
+// packetReader reads the packets of a capture file.
+type packetReader interface {
+	// readPacket returns the next packet, or io.EOF at the end of the capture.
+	readPacket() (packet, error)
+}
+
+// newPacketReader detects the format of a capture and creates a reader for it.
+//
+// Parameters:
+//   - r: The reader of the pcap or pcapng capture.
+//
+// Returns:
+//   - The packet reader.
+//   - ErrFormat if the format is unknown, or an error if reading fails.
+func newPacketReader(r io.Reader) (packetReader, error) {
+	buffered := bufio.NewReader(r)
+	magic, err := buffered.Peek(4)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrFormat, err)
+	}
+	if binary.LittleEndian.Uint32(magic) == pcapngSectionHeader {
+		return &pcapngReader{r: buffered}, nil
+	}
+	return newPcapReader(buffered)
+}
+
+// pcapReader reads packets from a pcap file.
+type pcapReader struct {
+	r          io.Reader
+	order      binary.ByteOrder // Byte order of the file.
+	resolution uint64           // Timestamp units per second of the fraction field.
+	linkType   uint16           // Link layer type of all packets.
+}
+
+// newPcapReader reads the file header of a pcap file.
+//
+// Parameters:
+//   - r: The reader of the pcap file.
+//
+// Returns:
+//   - A pointer to the created pcapReader.
+//   - ErrFormat if the magic number is unknown, or an error if reading fails.
+func newPcapReader(r io.Reader) (*pcapReader, error) {
+	header := make([]byte, 24)
+	if _, err := io.ReadFull(r, header); err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrFormat, err)
+	}
+	reader := &pcapReader{r: r}
+	for _, order := range []binary.ByteOrder{binary.LittleEndian, binary.BigEndian} {
+		switch order.Uint32(header[0:4]) {
+		case pcapMagicMicroseconds:
+			reader.order, reader.resolution = order, 1e6
+		case pcapMagicNanoseconds:
+			reader.order, reader.resolution = order, 1e9
+		}
+	}
+	if reader.order == nil {
+		return nil, fmt.Errorf("%w: unknown magic number %X", ErrFormat, header[0:4])
+	}
+	reader.linkType = uint16(reader.order.Uint32(header[20:24]))
+	return reader, nil
+}
+
+// readPacket returns the next packet of the pcap file.
+//
+// Returns:
+//   - The packet.
+//   - io.EOF at the end of the file, or an error if the file is truncated or malformed.
+func (pr *pcapReader) readPacket() (packet, error) {
+	header := make([]byte, 16)
+	if _, err := io.ReadFull(pr.r, header); err != nil {
+		return packet{}, truncated(err)
+	}
+	length := pr.order.Uint32(header[8:12])
+	if length > maxCaptureLength {
+		return packet{}, fmt.Errorf("%w: packet of %d bytes", ErrFormat, length)
+	}
+	data := make([]byte, length)
+	if _, err := io.ReadFull(pr.r, data); err != nil {
+		return packet{}, truncated(unexpectedEOF(err))
+	}
+	seconds := uint64(pr.order.Uint32(header[0:4]))
+	fraction := uint64(pr.order.Uint32(header[4:8]))
+	return packet{
+		time:     timestamp(seconds*pr.resolution+fraction, pr.resolution),
+		linkType: pr.linkType,
+		data:     data,
+	}, nil
+}
+
+// pcapngInterface is an interface described in a pcapng section.
+type pcapngInterface struct {
+	linkType   uint16 // Link layer type of the interface.
+	resolution uint64 // Timestamp units per second.
+}
+
+// pcapngReader reads packets from a pcapng file.
+type pcapngReader struct {
+	r          io.Reader
+	order      binary.ByteOrder  // Byte order of the current section.
+	interfaces []pcapngInterface // Interfaces of the current section.
+}
+
+// readPacket returns the next packet of the pcapng file, skipping blocks
+// other than packet blocks.
+//
+// Returns:
+//   - The packet.
+//   - io.EOF at the end of the file, or an error if the file is truncated or malformed.
+func (pr *pcapngReader) readPacket() (packet, error) {
+	for {
+		blockType, body, err := pr.readBlock()
+		if err != nil {
+			return packet{}, err
+		}
+		switch blockType {
+		case pcapngSectionHeader:
+			pr.interfaces = nil
+		case pcapngInterfaceDescription:
+			if len(body) < 8 {
+				return packet{}, fmt.Errorf("%w: short interface description block", ErrFormat)
+			}
+			pr.interfaces = append(pr.interfaces, pcapngInterface{
+				linkType:   pr.order.Uint16(body[0:2]),
+				resolution: pr.resolution(body[8:]),
+			})
+		case pcapngEnhancedPacket, pcapngPacket:
+			if len(body) < 20 {
+				return packet{}, fmt.Errorf("%w: short packet block", ErrFormat)
+			}
+			var index int
+			if blockType == pcapngEnhancedPacket {
+				index = int(pr.order.Uint32(body[0:4]))
+			} else {
+				index = int(pr.order.Uint16(body[0:2]))
+			}
+			if index >= len(pr.interfaces) {
+				return packet{}, fmt.Errorf("%w: unknown interface %d", ErrFormat, index)
+			}
+			length := pr.order.Uint32(body[12:16])
+			if uint64(length) > uint64(len(body)-20) {
+				return packet{}, fmt.Errorf("%w: packet exceeds its block", ErrFormat)
+			}
+			units := uint64(pr.order.Uint32(body[4:8]))<<32 | uint64(pr.order.Uint32(body[8:12]))
+			return packet{
+				time:     timestamp(units, pr.interfaces[index].resolution),
+				linkType: pr.interfaces[index].linkType,
+				data:     body[20 : 20+length],
+			}, nil
+		case pcapngSimplePacket:
+			if len(body) < 4 || len(pr.interfaces) == 0 {
+				return packet{}, fmt.Errorf("%w: invalid simple packet block", ErrFormat)
+			}
+			length := min(int(pr.order.Uint32(body[0:4])), len(body)-4)
+			return packet{
+				linkType: pr.interfaces[0].linkType,
+				data:     body[4 : 4+length],
+			}, nil
+		}
+	}
+}
+
+// readBlock reads the next block of the pcapng file. A section header block
+// sets the byte order of the blocks that follow it.
+//
+// Returns:
+//   - blockType: The type of the block.
+//   - body: The body of the block, without type and lengths.
+//   - err: io.EOF at the end of the file, or an error if the file is truncated or malformed.
+func (pr *pcapngReader) readBlock() (blockType uint32, body []byte, err error) {
+	head := make([]byte, 8)
+	if _, err = io.ReadFull(pr.r, head); err != nil {
+		return 0, nil, truncated(err)
+	}
+	blockType = binary.LittleEndian.Uint32(head[0:4])
+	if blockType == pcapngSectionHeader {
+		magic := make([]byte, 4)
+		if _, err = io.ReadFull(pr.r, magic); err != nil {
+			return 0, nil, truncated(unexpectedEOF(err))
+		}
+		switch {
+		case binary.LittleEndian.Uint32(magic) == pcapngByteOrderMagic:
+			pr.order = binary.LittleEndian
+		case binary.BigEndian.Uint32(magic) == pcapngByteOrderMagic:
+			pr.order = binary.BigEndian
+		default:
+			return 0, nil, fmt.Errorf("%w: unknown byte order magic %X", ErrFormat, magic)
+		}
+		head = append(head, magic...)
+	} else if pr.order == nil {
+		return 0, nil, fmt.Errorf("%w: missing section header block", ErrFormat)
+	}
+	blockType = pr.order.Uint32(head[0:4])
+
+	length := pr.order.Uint32(head[4:8])
+	if length < 12 || length%4 != 0 || length > maxCaptureLength || int(length) < len(head)+4 {
+		return 0, nil, fmt.Errorf("%w: block of %d bytes", ErrFormat, length)
+	}
+	block := make([]byte, length)
+	copy(block, head)
+	if _, err = io.ReadFull(pr.r, block[len(head):]); err != nil {
+		return 0, nil, truncated(unexpectedEOF(err))
+	}
+	return blockType, block[8 : length-4], nil
+}
+
+// resolution returns the timestamp resolution from the options of an
+// interface description block.
+//
+// Parameters:
+//   - options: The options of the block.
+//
+// Returns:
+//   - The timestamp units per second, 1e6 if the option is missing or unsupported.
+func (pr *pcapngReader) resolution(options []byte) uint64 {
+	for len(options) >= 4 {
+		code := pr.order.Uint16(options[0:2])
+		length := int(pr.order.Uint16(options[2:4]))
+		if len(options) < 4+length {
+			break
+		}
+		if code == pcapngOptionTimestampResolution && length >= 1 {
+			value := options[4]
+			if value&0x80 != 0 {
+				if exponent := value & 0x7F; exponent < 64 {
+					return 1 << exponent
+				}
+			} else if value <= 19 {
+				resolution := uint64(1)
+				for range value {
+					resolution *= 10
+				}
+				return resolution
+			}
+			break
+		}
+		options = options[4+(length+3)&^3:]
+	}
+	return 1e6
+}
+
+// timestamp converts a timestamp in units since the Unix epoch into a time.
+//
+// Parameters:
+//   - units: The timestamp.
+//   - resolution: The units per second.
+//
+// Returns:
+//   - The time in UTC.
+func timestamp(units, resolution uint64) time.Time {
+	seconds, fraction := units/resolution, units%resolution
+	hi, lo := bits.Mul64(fraction, 1e9)
+	nanoseconds, _ := bits.Div64(hi, lo, resolution)
+	return time.Unix(int64(seconds), int64(nanoseconds)).UTC()
+}
+
+// truncated reports a capture that ends in the middle of a record as malformed.
+//
+// Parameters:
+//   - err: The error of reading the record.
+//
+// Returns:
+//   - io.EOF at a record boundary, a wrapped ErrFormat for a truncated record, or err.
+func truncated(err error) error {
+	if errors.Is(err, io.ErrUnexpectedEOF) {
+		return fmt.Errorf("%w: truncated record", ErrFormat)
+	}
+	return err
+}
+
+// unexpectedEOF converts io.EOF into io.ErrUnexpectedEOF for reads inside a record.
+//
+// Parameters:
+//   - err: The error of the read.
+//
+// Returns:
+//   - io.ErrUnexpectedEOF if err is io.EOF, otherwise err.
+func unexpectedEOF(err error) error {
+	if err == io.EOF {
+		return io.ErrUnexpectedEOF
+	}
+	return err
+}
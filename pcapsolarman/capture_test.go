@@ -0,0 +1,212 @@
+package pcapsolarman
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"io"
+	"net/netip"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/tlmnb/gosolarman"
+)
+
+var (
+	client = netip.MustParseAddrPort("192.168.1.5:51234")
+	logger = netip.MustParseAddrPort("192.168.1.10:8899")
+	start  = time.Date(2026, 1, 2, 3, 4, 5, 123456000, time.UTC)
+)
+
+// ethernetPacket builds an Ethernet frame carrying an IPv4 TCP segment.
+func ethernetPacket(source, destination netip.AddrPort, sequence uint32, flags byte, payload []byte) []byte {
+	tcp := make([]byte, 20, 20+len(payload))
+	binary.BigEndian.PutUint16(tcp[0:2], source.Port())
+	binary.BigEndian.PutUint16(tcp[2:4], destination.Port())
+	binary.BigEndian.PutUint32(tcp[4:8], sequence)
+	tcp[12] = 5 << 4
+	tcp[13] = flags | 0x10
+	tcp = append(tcp, payload...)
+
+	ip := make([]byte, 20, 20+len(tcp))
+	ip[0] = 0x45
+	binary.BigEndian.PutUint16(ip[2:4], uint16(20+len(tcp)))
+	ip[8] = 64
+	ip[9] = protocolTCP
+	copy(ip[12:16], source.Addr().AsSlice())
+	copy(ip[16:20], destination.Addr().AsSlice())
+	ip = append(ip, tcp...)
+
+	ethernet := make([]byte, 14, 14+len(ip))
+	binary.BigEndian.PutUint16(ethernet[12:14], etherTypeIPv4)
+	return append(ethernet, ip...)
+}
+
+// pcapFile builds a pcap file with microsecond timestamps of Ethernet packets
+// captured one millisecond apart.
+func pcapFile(order binary.AppendByteOrder, packets ...[]byte) []byte {
+	file := order.AppendUint32(nil, pcapMagicMicroseconds)
+	file = order.AppendUint16(file, 2)
+	file = order.AppendUint16(file, 4)
+	file = append(file, make([]byte, 8)...)
+	file = order.AppendUint32(file, 65535)
+	file = order.AppendUint32(file, linkTypeEthernet)
+	for i, data := range packets {
+		captured := start.Add(time.Duration(i) * time.Millisecond)
+		file = order.AppendUint32(file, uint32(captured.Unix()))
+		file = order.AppendUint32(file, uint32(captured.Nanosecond()/1000))
+		file = order.AppendUint32(file, uint32(len(data)))
+		file = order.AppendUint32(file, uint32(len(data)))
+		file = append(file, data...)
+	}
+	return file
+}
+
+// pcapngFile builds a pcapng file with nanosecond timestamps of Ethernet
+// packets captured one millisecond apart, with a comment block in between.
+func pcapngFile(order binary.AppendByteOrder, packets ...[]byte) []byte {
+	block := func(blockType uint32, body []byte) []byte {
+		for len(body)%4 != 0 {
+			body = append(body, 0)
+		}
+		b := order.AppendUint32(nil, blockType)
+		b = order.AppendUint32(b, uint32(12+len(body)))
+		b = append(b, body...)
+		return order.AppendUint32(b, uint32(12+len(body)))
+	}
+
+	section := order.AppendUint32(nil, pcapngByteOrderMagic)
+	section = order.AppendUint16(section, 1)
+	section = order.AppendUint16(section, 0)
+	section = append(section, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF)
+	file := block(pcapngSectionHeader, section)
+
+	iface := order.AppendUint16(nil, linkTypeEthernet)
+	iface = append(iface, 0, 0)
+	iface = order.AppendUint32(iface, 65535)
+	iface = order.AppendUint16(iface, pcapngOptionTimestampResolution)
+	iface = order.AppendUint16(iface, 1)
+	iface = append(iface, 9, 0, 0, 0)
+	iface = append(iface, 0, 0, 0, 0)
+	file = append(file, block(pcapngInterfaceDescription, iface)...)
+	file = append(file, block(0x0000000A, []byte("comment"))...)
+
+	for i, data := range packets {
+		units := uint64(start.Add(time.Duration(i) * time.Millisecond).UnixNano())
+		body := order.AppendUint32(nil, 0)
+		body = order.AppendUint32(body, uint32(units>>32))
+		body = order.AppendUint32(body, uint32(units))
+		body = order.AppendUint32(body, uint32(len(data)))
+		body = order.AppendUint32(body, uint32(len(data)))
+		body = append(body, data...)
+		file = append(file, block(pcapngEnhancedPacket, body)...)
+	}
+	return file
+}
+
+func TestReadPackets(t *testing.T) {
+	packets := [][]byte{
+		ethernetPacket(client, logger, 1000, 0, []byte{0x01}),
+		ethernetPacket(logger, client, 2000, 0, []byte{0x02, 0x03}),
+	}
+	tests := []struct {
+		name    string
+		capture []byte
+	}{
+		{"pcap little endian", pcapFile(binary.LittleEndian, packets...)},
+		{"pcap big endian", pcapFile(binary.BigEndian, packets...)},
+		{"pcapng little endian", pcapngFile(binary.LittleEndian, packets...)},
+		{"pcapng big endian", pcapngFile(binary.BigEndian, packets...)},
+	}
+	for _, test := range tests {
+		reader, err := newPacketReader(bytes.NewReader(test.capture))
+		if err != nil {
+			t.Fatalf("%s: newPacketReader failed: %v", test.name, err)
+		}
+		for i, expected := range packets {
+			p, err := reader.readPacket()
+			if err != nil {
+				t.Fatalf("%s: readPacket failed: %v", test.name, err)
+			}
+			if captured := start.Add(time.Duration(i) * time.Millisecond); !p.time.Equal(captured) {
+				t.Errorf("%s: expected time %v, got %v", test.name, captured, p.time)
+			}
+			if p.linkType != linkTypeEthernet || !bytes.Equal(p.data, expected) {
+				t.Errorf("%s: expected Ethernet packet %X, got type %d packet %X", test.name, expected, p.linkType, p.data)
+			}
+		}
+		if _, err := reader.readPacket(); err != io.EOF {
+			t.Errorf("%s: expected io.EOF, got %v", test.name, err)
+		}
+	}
+}
+
+func TestReadPacketsInvalid(t *testing.T) {
+	if _, err := newPacketReader(bytes.NewReader([]byte("not a capture file at all"))); !errors.Is(err, ErrFormat) {
+		t.Errorf("Expected ErrFormat for an unknown format, got %v", err)
+	}
+
+	capture := pcapFile(binary.LittleEndian, ethernetPacket(client, logger, 1000, 0, []byte{0x01}))
+	reader, err := newPacketReader(bytes.NewReader(capture[:len(capture)-1]))
+	if err != nil {
+		t.Fatalf("newPacketReader failed: %v", err)
+	}
+	if _, err := reader.readPacket(); !errors.Is(err, ErrFormat) {
+		t.Errorf("Expected ErrFormat for a truncated packet, got %v", err)
+	}
+}
+
+func TestReadCloudCapture(t *testing.T) {
+	stick := netip.MustParseAddrPort("192.168.1.10:51000")
+	cloud := netip.MustParseAddrPort("47.88.8.8:10000")
+	data, err := (&gosolarman.Frame{
+		Header:  &gosolarman.Header{ControlCode: gosolarman.ControlCodeData, SequenceNumber: 0x0100, LoggerSerialNumber: 1234567891},
+		Payload: &gosolarman.LoggerPayload{FrameType: 0x01, SensorType: 0x0102, PowerOnTime: 3600, Data: []byte{0x01, 0x02, 0x03, 0x04}},
+	}).Marshal()
+	if err != nil {
+		t.Fatalf("Marshal failed: %v", err)
+	}
+	// The cloud acknowledges data frames with control code 0x1210.
+	reply, err := (&gosolarman.Frame{
+		Header:  &gosolarman.Header{ControlCode: 0x1210, SequenceNumber: 0x0100, LoggerSerialNumber: 1234567891},
+		Payload: &gosolarman.RawPayload{0x01, 0x00},
+	}).Marshal()
+	if err != nil {
+		t.Fatalf("Marshal failed: %v", err)
+	}
+	capture := pcapFile(binary.LittleEndian,
+		ethernetPacket(stick, cloud, 1000, 0, data),
+		ethernetPacket(cloud, stick, 2000, 0, reply),
+	)
+
+	events, err := ReadAll(bytes.NewReader(capture))
+	if err != nil {
+		t.Fatalf("ReadAll failed: %v", err)
+	}
+	expected := []string{
+		"192.168.1.10:51000 -> 47.88.8.8:10000 data seq=0x0100 serial=1234567891 type=0x01 sensor=0x0102 uptime=3600s data=4 bytes",
+		"47.88.8.8:10000 -> 192.168.1.10:51000 0x1210 seq=0x0100 serial=1234567891 payload=0100",
+	}
+	if len(events) != len(expected) {
+		t.Fatalf("Expected %d events, got %d", len(expected), len(events))
+	}
+	for i, event := range events {
+		if event.Err != nil {
+			t.Errorf("Event %d: unexpected error %v", i, event.Err)
+		}
+		if annotated := event.String(); !strings.HasSuffix(annotated, expected[i]) {
+			t.Errorf("Event %d: expected annotation ending in %q, got %q", i, expected[i], annotated)
+		}
+	}
+
+	// Without port 10000, the cloud traffic is ignored.
+	reader, err := NewReader(bytes.NewReader(capture))
+	if err != nil {
+		t.Fatalf("NewReader failed: %v", err)
+	}
+	reader.Ports = []uint16{8899}
+	if event, err := reader.Next(); err != io.EOF {
+		t.Errorf("Expected io.EOF, got %v (%v)", event, err)
+	}
+}
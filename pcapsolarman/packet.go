@@ -0,0 +1,164 @@
+package pcapsolarman
+
+import (
+	"encoding/binary"
+	"net/netip"
+)
+
+const (
+	// Link layer types of the captures (see https://www.tcpdump.org/linktypes.html).
+	linkTypeNull      = 0
+	linkTypeEthernet  = 1
+	linkTypeRaw       = 101
+	linkTypeLoop      = 108
+	linkTypeLinuxSLL  = 113
+	linkTypeIPv4      = 228
+	linkTypeIPv6      = 229
+	linkTypeLinuxSLL2 = 276
+
+	// Ethernet types of the link layer headers.
+	etherTypeIPv4 = 0x0800
+	etherTypeIPv6 = 0x86DD
+	etherTypeVLAN = 0x8100
+	etherTypeQinQ = 0x88A8
+
+	// protocolTCP is the IP protocol number of TCP.
+	protocolTCP = 6
+
+	// TCP flags.
+	flagFIN = 0x01
+	flagSYN = 0x02
+	flagRST = 0x04
+)
+
+// segment is a decoded TCP segment.
+type segment struct {
+	source      netip.AddrPort // Address and port of the sender.
+	destination netip.AddrPort // Address and port of the receiver.
+	sequence    uint32         // Sequence number of the first payload byte (or of the SYN).
+	flags       byte           // TCP flags.
+	payload     []byte         // TCP payload.
+}
+
+// decodeSegment decodes the TCP segment of a link layer packet.
+//
+// Parameters:
+//   - linkType: The link layer type of the packet.
+//   - data: The packet, starting with the link layer header.
+//
+// Returns:
+//   - s: The TCP segment.
+//   - ok: false if the packet is not an unfragmented TCP segment over IPv4 or IPv6.
+func decodeSegment(linkType uint16, data []byte) (s segment, ok bool) {
+	ip, ok := networkLayer(linkType, data)
+	if !ok || len(ip) < 1 {
+		return segment{}, false
+	}
+
+	var tcp []byte
+	var source, destination netip.Addr
+	switch ip[0] >> 4 {
+	case 4:
+		if len(ip) < 20 {
+			return segment{}, false
+		}
+		headerLength := int(ip[0]&0x0F) * 4
+		totalLength := int(binary.BigEndian.Uint16(ip[2:4]))
+		fragment := binary.BigEndian.Uint16(ip[6:8])
+		if ip[9] != protocolTCP || fragment&0x3FFF != 0 || headerLength < 20 || totalLength < headerLength || totalLength > len(ip) {
+			return segment{}, false
+		}
+		source = netip.AddrFrom4([4]byte(ip[12:16]))
+		destination = netip.AddrFrom4([4]byte(ip[16:20]))
+		tcp = ip[headerLength:totalLength]
+	case 6:
+		if len(ip) < 40 {
+			return segment{}, false
+		}
+		payloadLength := int(binary.BigEndian.Uint16(ip[4:6]))
+		if 40+payloadLength > len(ip) {
+			return segment{}, false
+		}
+		source = netip.AddrFrom16([16]byte(ip[8:24]))
+		destination = netip.AddrFrom16([16]byte(ip[24:40]))
+		next, payload := ip[6], ip[40:40+payloadLength]
+		// Skip hop-by-hop, routing and destination options headers.
+		for next == 0 || next == 43 || next == 60 {
+			if len(payload) < 8 || len(payload) < (int(payload[1])+1)*8 {
+				return segment{}, false
+			}
+			next, payload = payload[0], payload[(int(payload[1])+1)*8:]
+		}
+		if next != protocolTCP {
+			return segment{}, false
+		}
+		tcp = payload
+	default:
+		return segment{}, false
+	}
+
+	if len(tcp) < 20 {
+		return segment{}, false
+	}
+	offset := int(tcp[12]>>4) * 4
+	if offset < 20 || offset > len(tcp) {
+		return segment{}, false
+	}
+	return segment{
+		source:      netip.AddrPortFrom(source, binary.BigEndian.Uint16(tcp[0:2])),
+		destination: netip.AddrPortFrom(destination, binary.BigEndian.Uint16(tcp[2:4])),
+		sequence:    binary.BigEndian.Uint32(tcp[4:8]),
+		flags:       tcp[13],
+		payload:     tcp[offset:],
+	}, true
+}
+
+// networkLayer strips the link layer header of a packet.
+//
+// Parameters:
+//   - linkType: The link layer type of the packet.
+//   - data: The packet, starting with the link layer header.
+//
+// Returns:
+//   - ip: The IP packet.
+//   - ok: false if the link layer type is unsupported or the packet carries no IP packet.
+func networkLayer(linkType uint16, data []byte) (ip []byte, ok bool) {
+	var etherType uint16
+	switch linkType {
+	case linkTypeRaw, linkTypeIPv4, linkTypeIPv6:
+		return data, true
+	case linkTypeNull, linkTypeLoop:
+		// The address family is in host byte order, so rely on the IP version instead.
+		if len(data) < 4 {
+			return nil, false
+		}
+		return data[4:], true
+	case linkTypeEthernet:
+		if len(data) < 14 {
+			return nil, false
+		}
+		etherType, data = binary.BigEndian.Uint16(data[12:14]), data[14:]
+		for etherType == etherTypeVLAN || etherType == etherTypeQinQ {
+			if len(data) < 4 {
+				return nil, false
+			}
+			etherType, data = binary.BigEndian.Uint16(data[2:4]), data[4:]
+		}
+	case linkTypeLinuxSLL:
+		if len(data) < 16 {
+			return nil, false
+		}
+		etherType, data = binary.BigEndian.Uint16(data[14:16]), data[16:]
+	case linkTypeLinuxSLL2:
+		if len(data) < 20 {
+			return nil, false
+		}
+		etherType, data = binary.BigEndian.Uint16(data[0:2]), data[20:]
+	default:
+		return nil, false
+	}
+	if etherType != etherTypeIPv4 && etherType != etherTypeIPv6 {
+		return nil, false
+	}
+	return data, true
+}
@@ -0,0 +1,244 @@
+// Package pcapsolarman reads Solarman V5 traffic from pcap and pcapng
+// captures, e.g. taken with tcpdump between a data logging stick and a client
+// or the Solarman cloud. It reassembles the TCP streams and decodes every
+// frame into a timeline of events.
+package pcapsolarman
+
+import (
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/netip"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/grid-x/modbus"
+	"github.com/tlmnb/gosolarman"
+)
+
+// DefaultPorts are the TCP ports of Solarman V5 traffic: 8899 for local
+// clients and 10000 for the Solarman cloud.
+var DefaultPorts = []uint16{8899, 10000}
+
+// controlCodeNames are the names of the known control codes.
+var controlCodeNames = map[uint16]string{
+	gosolarman.ControlCodeRequest:   "request",
+	gosolarman.ControlCodeResponse:  "response",
+	gosolarman.ControlCodeHandshake: "handshake",
+	gosolarman.ControlCodeData:      "data",
+	gosolarman.ControlCodeInfo:      "info",
+	gosolarman.ControlCodeHeartbeat: "heartbeat",
+	gosolarman.ControlCodeReport:    "report",
+}
+
+// Event is a Solarman frame found in a capture.
+type Event struct {
+	Time        time.Time          // Capture time of the packet that completed the frame.
+	Source      netip.AddrPort     // Address and port of the sender.
+	Destination netip.AddrPort     // Address and port of the receiver.
+	Raw         []byte             // The raw frame, from StartByte to EndByte inclusive.
+	Header      *gosolarman.Header // The parsed header, or nil if it is invalid.
+	Frame       *gosolarman.Frame  // The parsed frame, or nil if Err is set.
+	Err         error              // The error of parsing the frame (e.g., a FrameError).
+}
+
+// Reader reads the events of a capture in capture order.
+type Reader struct {
+	Ports []uint16 // TCP ports of Solarman V5 traffic, DefaultPorts by default.
+
+	packets packetReader
+	streams map[flow]*stream // Streams by direction.
+	queue   []*Event         // Events completed by the last packet.
+}
+
+// NewReader creates a new reader of a pcap or pcapng capture.
+//
+// Parameters:
+//   - r: The reader of the capture.
+//
+// Returns:
+//   - A pointer to the created Reader.
+//   - ErrFormat if the capture is neither pcap nor pcapng, or an error if reading fails.
+func NewReader(r io.Reader) (*Reader, error) {
+	packets, err := newPacketReader(r)
+	if err != nil {
+		return nil, err
+	}
+	return &Reader{
+		Ports:   DefaultPorts,
+		packets: packets,
+		streams: make(map[flow]*stream),
+	}, nil
+}
+
+// Next returns the next event of the capture.
+//
+// Returns:
+//   - The next event.
+//   - io.EOF at the end of the capture, or an error if the capture is malformed.
+func (r *Reader) Next() (*Event, error) {
+	for len(r.queue) == 0 {
+		p, err := r.packets.readPacket()
+		if err != nil {
+			return nil, err
+		}
+		s, ok := decodeSegment(p.linkType, p.data)
+		if !ok || !slices.Contains(r.Ports, s.source.Port()) && !slices.Contains(r.Ports, s.destination.Port()) {
+			continue
+		}
+		key := flow{source: s.source, destination: s.destination}
+		st, ok := r.streams[key]
+		if !ok {
+			st = newStream()
+			r.streams[key] = st
+		}
+		for _, raw := range st.add(s) {
+			r.queue = append(r.queue, newEvent(p.time, s, raw))
+		}
+		if s.flags&(flagFIN|flagRST) != 0 {
+			delete(r.streams, key)
+		}
+	}
+	event := r.queue[0]
+	r.queue = r.queue[1:]
+	return event, nil
+}
+
+// ReadAll reads all events of a capture.
+//
+// Parameters:
+//   - r: The reader of the pcap or pcapng capture.
+//
+// Returns:
+//   - The events in capture order.
+//   - An error if the capture is malformed.
+func ReadAll(r io.Reader) ([]*Event, error) {
+	reader, err := NewReader(r)
+	if err != nil {
+		return nil, err
+	}
+	var events []*Event
+	for {
+		event, err := reader.Next()
+		if err == io.EOF {
+			return events, nil
+		}
+		if err != nil {
+			return events, err
+		}
+		events = append(events, event)
+	}
+}
+
+// newEvent parses a frame found in a segment.
+//
+// Parameters:
+//   - captured: The capture time of the packet.
+//   - s: The segment that completed the frame.
+//   - raw: The raw frame.
+//
+// Returns:
+//   - The event of the frame.
+func newEvent(captured time.Time, s segment, raw []byte) *Event {
+	event := &Event{
+		Time:        captured,
+		Source:      s.source,
+		Destination: s.destination,
+		Raw:         raw,
+	}
+	event.Header, _ = gosolarman.ParseHeader(raw)
+	event.Frame, event.Err = gosolarman.ParseFrame(raw)
+	return event
+}
+
+// ControlCodeName returns the name of the control code of the frame.
+//
+// Returns:
+//   - The name (e.g., "request"), the control code in hex if it is unknown,
+//     or "invalid" if the header is invalid.
+func (e *Event) ControlCodeName() string {
+	if e.Header == nil {
+		return "invalid"
+	}
+	if name, ok := controlCodeNames[e.Header.ControlCode]; ok {
+		return name
+	}
+	return fmt.Sprintf("0x%04X", e.Header.ControlCode)
+}
+
+// String annotates the event on one line with its time, direction, control
+// code, sequence number, logger serial number and a summary of the payload.
+//
+// Returns:
+//   - The annotated event.
+func (e *Event) String() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "%s %s -> %s %s", e.Time.Format("2006-01-02T15:04:05.000000Z07:00"), e.Source, e.Destination, e.ControlCodeName())
+	if e.Header != nil {
+		fmt.Fprintf(&b, " seq=0x%04X serial=%d", e.Header.SequenceNumber, e.Header.LoggerSerialNumber)
+	}
+	if e.Frame != nil {
+		switch payload := e.Frame.Payload.(type) {
+		case *gosolarman.RequestPayload:
+			fmt.Fprintf(&b, " slave=%d %s", payload.SlaveID, summarizeRequest(&payload.ModbusRTUFrame))
+		case *gosolarman.ResponsePayload:
+			fmt.Fprintf(&b, " status=0x%02X slave=%d %s", payload.Status, payload.SlaveID, summarizeResponse(&payload.ModbusRTUFrame))
+		case *gosolarman.UnreachablePayload:
+			fmt.Fprintf(&b, " status=0x%02X uptime=%ds inverter unreachable", payload.Status, payload.PowerOnTime)
+			if len(payload.Code) > 0 {
+				fmt.Fprintf(&b, " code=%s", hex.EncodeToString(payload.Code))
+			}
+		case *gosolarman.LoggerPayload:
+			fmt.Fprintf(&b, " type=0x%02X sensor=0x%04X uptime=%ds data=%d bytes", payload.FrameType, payload.SensorType, payload.PowerOnTime, len(payload.Data))
+		case *gosolarman.HeartbeatPayload:
+			fmt.Fprintf(&b, " data=%s", hex.EncodeToString(payload.Data))
+		default:
+			data, _ := payload.MarshalBinary()
+			fmt.Fprintf(&b, " payload=%s", hex.EncodeToString(data))
+		}
+	}
+	if e.Err != nil {
+		fmt.Fprintf(&b, " error=%q", e.Err.Error())
+	}
+	return b.String()
+}
+
+// summarizeRequest describes a Modbus request PDU.
+//
+// Parameters:
+//   - pdu: The request PDU.
+//
+// Returns:
+//   - The function code with the address and quantity or value, if known.
+func summarizeRequest(pdu *modbus.ProtocolDataUnit) string {
+	summary := fmt.Sprintf("fc=0x%02X", pdu.FunctionCode)
+	if len(pdu.Data) < 4 {
+		return summary + " data=" + hex.EncodeToString(pdu.Data)
+	}
+	address := binary.BigEndian.Uint16(pdu.Data[0:2])
+	switch pdu.FunctionCode {
+	case modbus.FuncCodeWriteSingleCoil, modbus.FuncCodeWriteSingleRegister:
+		return fmt.Sprintf("%s address=0x%04X value=0x%04X", summary, address, binary.BigEndian.Uint16(pdu.Data[2:4]))
+	case modbus.FuncCodeReadCoils, modbus.FuncCodeReadDiscreteInputs,
+		modbus.FuncCodeReadHoldingRegisters, modbus.FuncCodeReadInputRegisters,
+		modbus.FuncCodeWriteMultipleCoils, modbus.FuncCodeWriteMultipleRegisters:
+		return fmt.Sprintf("%s address=0x%04X quantity=%d", summary, address, binary.BigEndian.Uint16(pdu.Data[2:4]))
+	}
+	return summary + " data=" + hex.EncodeToString(pdu.Data)
+}
+
+// summarizeResponse describes a Modbus response PDU.
+//
+// Parameters:
+//   - pdu: The response PDU.
+//
+// Returns:
+//   - The function code with the exception code or the data.
+func summarizeResponse(pdu *modbus.ProtocolDataUnit) string {
+	if pdu.FunctionCode&0x80 != 0 && len(pdu.Data) > 0 {
+		return fmt.Sprintf("fc=0x%02X exception=0x%02X", pdu.FunctionCode, pdu.Data[0])
+	}
+	return fmt.Sprintf("fc=0x%02X data=%s", pdu.FunctionCode, hex.EncodeToString(pdu.Data))
+}
@@ -0,0 +1,126 @@
+package pcapsolarman
+
+import (
+	"bytes"
+	"encoding/binary"
+	"net/netip"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/grid-x/modbus"
+	"github.com/tlmnb/gosolarman"
+)
+
+// requestFrame builds a request to read holding registers.
+func requestFrame(t *testing.T, sequence uint16, address, quantity uint16) []byte {
+	t.Helper()
+	frame, err := (&gosolarman.Request{
+		Header: &gosolarman.Header{ControlCode: gosolarman.ControlCodeRequest, SequenceNumber: sequence, LoggerSerialNumber: 1234567891},
+		Payload: &gosolarman.RequestPayload{
+			FrameType:      0x02,
+			SlaveID:        0x01,
+			ModbusRTUFrame: modbus.ProtocolDataUnit{FunctionCode: modbus.FuncCodeReadHoldingRegisters, Data: binary.BigEndian.AppendUint16(binary.BigEndian.AppendUint16(nil, address), quantity)},
+		},
+	}).Marshal()
+	if err != nil {
+		t.Fatalf("Marshal failed: %v", err)
+	}
+	return frame
+}
+
+// responseFrame builds a response carrying one register.
+func responseFrame(t *testing.T, sequence uint16, value uint16) []byte {
+	t.Helper()
+	frame, err := (&gosolarman.Response{
+		Header: &gosolarman.Header{ControlCode: gosolarman.ControlCodeResponse, SequenceNumber: sequence, LoggerSerialNumber: 1234567891},
+		Payload: &gosolarman.ResponsePayload{
+			FrameType:      0x02,
+			Status:         0x01,
+			SlaveID:        0x01,
+			ModbusRTUFrame: modbus.ProtocolDataUnit{FunctionCode: modbus.FuncCodeReadHoldingRegisters, Data: append([]byte{0x02}, binary.BigEndian.AppendUint16(nil, value)...)},
+		},
+	}).Marshal()
+	if err != nil {
+		t.Fatalf("Marshal failed: %v", err)
+	}
+	return frame
+}
+
+func TestReadAll(t *testing.T) {
+	first := requestFrame(t, 0x0101, 0x0010, 1)
+	second := requestFrame(t, 0x0102, 0x0020, 1)
+	response := responseFrame(t, 0x0101, 0x002A)
+	heartbeat, _ := (&gosolarman.Frame{
+		Header:  &gosolarman.Header{ControlCode: gosolarman.ControlCodeHeartbeat, SequenceNumber: 0x0A00, LoggerSerialNumber: 1234567891},
+		Payload: &gosolarman.HeartbeatPayload{Data: []byte{0x00}},
+	}).Marshal()
+	other := netip.MustParseAddrPort("192.168.1.20:443")
+
+	// The client stream starts with a SYN, carries garbage, a request split in
+	// two segments and a request that arrives before its retransmitted start.
+	const isn = 1000
+	stream := append(append([]byte{0x00, 0xFF}, first...), second...)
+	packets := [][]byte{
+		ethernetPacket(client, logger, isn, flagSYN, nil),
+		ethernetPacket(client, other, 1, 0, first),
+		ethernetPacket(client, logger, isn+1, 0, stream[:10]),
+		ethernetPacket(client, logger, isn+11, 0, stream[10:2+len(first)]),
+		ethernetPacket(logger, client, 5000, 0, response),
+		ethernetPacket(client, logger, isn+1+2+uint32(len(first))+5, 0, stream[2+len(first)+5:]),
+		ethernetPacket(client, logger, isn+1, 0, stream[:2+len(first)+5]),
+		ethernetPacket(logger, client, 5000+uint32(len(response)), 0, heartbeat),
+	}
+
+	events, err := ReadAll(bytes.NewReader(pcapngFile(binary.LittleEndian, packets...)))
+	if err != nil {
+		t.Fatalf("ReadAll failed: %v", err)
+	}
+	expected := []struct {
+		raw       []byte
+		source    netip.AddrPort
+		packet    int // Index of the packet that completed the frame.
+		annotated string
+	}{
+		{first, client, 3, "request seq=0x0101 serial=1234567891 slave=1 fc=0x03 address=0x0010 quantity=1"},
+		{response, logger, 4, "response seq=0x0101 serial=1234567891 status=0x01 slave=1 fc=0x03 data=02002a"},
+		{second, client, 6, "request seq=0x0102 serial=1234567891 slave=1 fc=0x03 address=0x0020 quantity=1"},
+		{heartbeat, logger, 7, "heartbeat seq=0x0A00 serial=1234567891 data=00"},
+	}
+	if len(events) != len(expected) {
+		t.Fatalf("Expected %d events, got %d", len(expected), len(events))
+	}
+	for i, event := range events {
+		if !bytes.Equal(event.Raw, expected[i].raw) || event.Source != expected[i].source || event.Err != nil {
+			t.Errorf("Event %d: expected %X from %s, got %X from %s (%v)", i, expected[i].raw, expected[i].source, event.Raw, event.Source, event.Err)
+		}
+		if captured := start.Add(time.Duration(expected[i].packet) * time.Millisecond); !event.Time.Equal(captured) {
+			t.Errorf("Event %d: expected time %v, got %v", i, captured, event.Time)
+		}
+		if annotated := event.String(); !strings.HasSuffix(annotated, expected[i].annotated) {
+			t.Errorf("Event %d: expected annotation ending in %q, got %q", i, expected[i].annotated, annotated)
+		}
+	}
+}
+
+func TestReadAllInverterUnreachable(t *testing.T) {
+	response := responseFrame(t, 0x0101, 0x002A)
+	// Keep only the fixed fields of the payload, like a stick without inverter.
+	unreachable := append([]byte(nil), response[:11+14]...)
+	unreachable[1], unreachable[2] = 14, 0
+	unreachable = append(unreachable, gosolarman.CheckSum(unreachable[1:]), gosolarman.EndByte)
+
+	events, err := ReadAll(bytes.NewReader(pcapFile(binary.BigEndian, ethernetPacket(logger, client, 1, 0, unreachable))))
+	if err != nil {
+		t.Fatalf("ReadAll failed: %v", err)
+	}
+	if len(events) != 1 {
+		t.Fatalf("Expected 1 event, got %d", len(events))
+	}
+	if _, ok := events[0].Frame.Payload.(*gosolarman.UnreachablePayload); !ok || events[0].Err != nil {
+		t.Errorf("Expected an UnreachablePayload, got %v", events[0].Err)
+	}
+	if annotated := events[0].String(); !strings.HasSuffix(annotated, "response seq=0x0101 serial=1234567891 status=0x01 uptime=0s inverter unreachable") {
+		t.Errorf("Expected an annotated response, got %q", annotated)
+	}
+}
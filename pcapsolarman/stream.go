@@ -0,0 +1,156 @@
+package pcapsolarman
+
+import (
+	"encoding/binary"
+	"net/netip"
+
+	"github.com/tlmnb/gosolarman"
+)
+
+const (
+	// headerLength is the length of a Solarman frame header including the start byte.
+	headerLength = 11
+
+	// trailerLength is the length of a Solarman frame trailer (checksum and end byte).
+	trailerLength = 2
+
+	// maxPayloadLength is the largest payload of a frame. Longer announced
+	// payloads are treated as garbage and skipped, like the frame reader of the client does.
+	maxPayloadLength = 1024
+
+	// maxPendingSegments is the largest number of out-of-order segments kept
+	// per direction. Beyond it, the missing bytes are assumed lost.
+	maxPendingSegments = 256
+)
+
+// flow is one direction of a TCP connection.
+type flow struct {
+	source      netip.AddrPort
+	destination netip.AddrPort
+}
+
+// stream reassembles the bytes of one direction of a TCP connection and
+// splits them into Solarman frames.
+type stream struct {
+	started bool              // Whether next is known.
+	next    uint32            // Sequence number of the next expected byte.
+	pending map[uint32][]byte // Out-of-order payloads by sequence number.
+	buffer  []byte            // Reassembled bytes that do not form a complete frame yet.
+}
+
+// newStream creates a new stream.
+//
+// Returns:
+//   - A pointer to the created stream.
+func newStream() *stream {
+	return &stream{pending: make(map[uint32][]byte)}
+}
+
+// add adds a segment to the stream.
+//
+// Parameters:
+//   - s: The segment of this direction.
+//
+// Returns:
+//   - The frames completed by the segment.
+func (st *stream) add(s segment) [][]byte {
+	if s.flags&flagSYN != 0 {
+		// A new connection on the same ports starts from scratch.
+		st.started, st.next = true, s.sequence+1
+		st.buffer, st.pending = nil, make(map[uint32][]byte)
+		return nil
+	}
+	if len(s.payload) == 0 {
+		return nil
+	}
+	if !st.started {
+		// The capture started in the middle of the connection.
+		st.started, st.next = true, s.sequence
+	}
+
+	if int32(s.sequence-st.next) > 0 {
+		st.pending[s.sequence] = append([]byte(nil), s.payload...)
+		if len(st.pending) > maxPendingSegments {
+			st.skipGap()
+		}
+	} else {
+		st.append(s.sequence, s.payload)
+	}
+	for progress := true; progress; {
+		progress = false
+		for sequence, payload := range st.pending {
+			if int32(sequence-st.next) <= 0 {
+				delete(st.pending, sequence)
+				st.append(sequence, payload)
+				progress = true
+			}
+		}
+	}
+	return st.frames()
+}
+
+// append appends the new bytes of an in-order payload, dropping bytes that
+// were already received.
+//
+// Parameters:
+//   - sequence: The sequence number of the first byte of the payload.
+//   - payload: The payload.
+func (st *stream) append(sequence uint32, payload []byte) {
+	overlap := int(st.next - sequence)
+	if overlap >= len(payload) {
+		return
+	}
+	st.buffer = append(st.buffer, payload[overlap:]...)
+	st.next += uint32(len(payload) - overlap)
+}
+
+// skipGap gives up on missing bytes and continues with the earliest pending
+// payload. The incomplete frame before the gap is dropped.
+func (st *stream) skipGap() {
+	first, found := uint32(0), false
+	for sequence := range st.pending {
+		if !found || int32(sequence-first) < 0 {
+			first, found = sequence, true
+		}
+	}
+	st.next = first
+	st.buffer = nil
+}
+
+// frames removes the complete frames from the buffer, skipping bytes that do
+// not belong to a frame.
+//
+// Returns:
+//   - The complete frames, from StartByte to EndByte inclusive.
+func (st *stream) frames() [][]byte {
+	var frames [][]byte
+	for {
+		start := 0
+		for start < len(st.buffer) && st.buffer[start] != gosolarman.StartByte {
+			start++
+		}
+		st.buffer = st.buffer[start:]
+		if len(st.buffer) < headerLength {
+			break
+		}
+		length := int(binary.LittleEndian.Uint16(st.buffer[1:3]))
+		if length > maxPayloadLength {
+			st.buffer = st.buffer[1:]
+			continue
+		}
+		size := headerLength + length + trailerLength
+		if len(st.buffer) < size {
+			break
+		}
+		if st.buffer[size-1] != gosolarman.EndByte {
+			st.buffer = st.buffer[1:]
+			continue
+		}
+		frames = append(frames, append([]byte(nil), st.buffer[:size]...))
+		st.buffer = st.buffer[size:]
+	}
+	if len(st.buffer) == 0 {
+		st.buffer = nil
+	}
+	return frames
+}
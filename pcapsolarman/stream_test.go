@@ -0,0 +1,42 @@
+package pcapsolarman
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestStreamResync(t *testing.T) {
+	frame := requestFrame(t, 0x0101, 0x0010, 1)
+	// A false start byte announcing a payload that does not end with EndByte.
+	garbage := []byte{0xA5, 0x02, 0x00, 0x10, 0x45, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00}
+
+	st := newStream()
+	frames := st.add(segment{sequence: 1, payload: append(garbage, frame...)})
+	if len(frames) != 1 || !bytes.Equal(frames[0], frame) {
+		t.Errorf("Expected %X, got %X", frame, frames)
+	}
+}
+
+func TestStreamLostSegment(t *testing.T) {
+	frame := requestFrame(t, 0x0101, 0x0010, 1)
+
+	st := newStream()
+	st.add(segment{sequence: 1, payload: frame[:5]})
+	// The rest of the first frame is never captured.
+	var frames [][]byte
+	for i := range maxPendingSegments + 1 {
+		sequence := uint32(100 + i*len(frame))
+		frames = append(frames, st.add(segment{sequence: sequence, payload: frame})...)
+	}
+	if len(frames) != maxPendingSegments+1 {
+		t.Fatalf("Expected %d frames after the gap, got %d", maxPendingSegments+1, len(frames))
+	}
+	for _, f := range frames {
+		if !bytes.Equal(f, frame) {
+			t.Errorf("Expected %X, got %X", frame, f)
+		}
+	}
+	if len(st.pending) != 0 {
+		t.Errorf("Expected no pending segments, got %d", len(st.pending))
+	}
+}
@@ -0,0 +1,246 @@
+package gosolarman
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"os"
+	"slices"
+	"time"
+
+	"github.com/grid-x/modbus"
+)
+
+// pipelineProbeInterval is the number of successful requests sent one at a
+// time after which pipelining is tried again on a device that failed to
+// answer pipelined requests (e.g., until its firmware was updated).
+const pipelineProbeInterval = 100
+
+// Future is the pending response to a pipelined request.
+type Future struct {
+	done     chan struct{}
+	response *modbus.ProtocolDataUnit
+	err      error
+}
+
+// newFuture creates a new unresolved future.
+//
+// Returns:
+//   - A pointer to the created Future.
+func newFuture() *Future {
+	return &Future{
+		done: make(chan struct{}),
+	}
+}
+
+// Done returns a channel that is closed when the response is available.
+func (f *Future) Done() <-chan struct{} {
+	return f.done
+}
+
+// Wait waits for the response to the request.
+//
+// Parameters:
+//   - ctx: The context limiting the wait.
+//
+// Returns:
+//   - response: The Modbus Protocol Data Unit received from the device.
+//   - err: The error of the request, or ctx.Err() if ctx is done first.
+func (f *Future) Wait(ctx context.Context) (response *modbus.ProtocolDataUnit, err error) {
+	select {
+	case <-f.done:
+		return f.response, f.err
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// resolve sets the result of the future and wakes up waiters.
+//
+// Parameters:
+//   - response: The Modbus Protocol Data Unit received from the device.
+//   - err: The error of the request.
+func (f *Future) resolve(response *modbus.ProtocolDataUnit, err error) {
+	f.response, f.err = response, err
+	close(f.done)
+}
+
+// SendPipelinedContext sends several PDUs to the device and returns a future
+// for each response. Up to the handler's PipelineWindow requests are written
+// back to back and the responses are matched by sequence number. If the
+// device fails to answer pipelined requests, the handler falls back to
+// sending one request at a time, and tries pipelining again after 100
+// successful requests.
+//
+// Parameters:
+//   - ctx: The context controlling the exchanges.
+//   - pdus: The Modbus Protocol Data Units to send.
+//
+// Returns:
+//   - The futures of the responses, in the order of pdus.
+func (c *ContextClient) SendPipelinedContext(ctx context.Context, pdus []*modbus.ProtocolDataUnit) []*Future {
+	futures := make([]*Future, len(pdus))
+	aduRequests := make([][]byte, 0, len(pdus))
+	indexes := make([]int, 0, len(pdus))
+	for i, pdu := range pdus {
+		futures[i] = newFuture()
+		aduRequest, err := c.handler.Encode(pdu)
+		if err != nil {
+			futures[i].resolve(nil, err)
+			continue
+		}
+		aduRequests = append(aduRequests, aduRequest)
+		indexes = append(indexes, i)
+	}
+
+	go c.handler.sendPipelined(ctx, aduRequests, func(j int, aduResponse []byte, err error) {
+		i := indexes[j]
+		var response *modbus.ProtocolDataUnit
+		if err == nil {
+			response, err = c.response(ctx, pdus[i], aduRequests[j], aduResponse)
+		}
+		futures[i].resolve(response, err)
+	})
+	return futures
+}
+
+// sendPipelined sends requests with up to PipelineWindow of them outstanding
+// and passes each response to deliver. Requests that could not be pipelined
+// are sent one at a time. The transporter must be locked.
+//
+// Parameters:
+//   - ctx: The context controlling the exchanges.
+//   - aduRequests: The Modbus RTU requests to send.
+//   - deliver: Called with the index and the response or error of each request.
+func (mb *solarmanTransporter) sendPipelined(ctx context.Context, aduRequests [][]byte, deliver func(i int, aduResponse []byte, err error)) {
+	var serial []int
+	if mb.PipelineWindow > 1 && !mb.pipelineUnsupported {
+		serial = mb.pipeline(ctx, aduRequests, deliver)
+	} else {
+		for i := range aduRequests {
+			serial = append(serial, i)
+		}
+	}
+	for _, i := range serial {
+		aduResponse, err := mb.send(ctx, aduRequests[i])
+		if err == nil && mb.pipelineUnsupported {
+			mb.serialSuccesses++
+			if mb.serialSuccesses >= pipelineProbeInterval {
+				mb.pipelineUnsupported = false
+				mb.log(ctx, slog.LevelInfo, "trying pipelining again", slog.Int("requests", mb.serialSuccesses))
+			}
+		}
+		deliver(i, aduResponse, err)
+	}
+}
+
+// pipeline writes requests back to back, keeping up to PipelineWindow of them
+// outstanding, and matches the responses by sequence number.
+// If the device stops answering, pipelining is disabled for the transporter.
+//
+// Parameters:
+//   - ctx: The context controlling the exchanges.
+//   - aduRequests: The Modbus RTU requests to send.
+//   - deliver: Called with the index and the response of each answered request.
+//
+// Returns:
+//   - unanswered: The indexes of the requests that were not answered or
+//     received an invalid response, which must be sent again.
+func (mb *solarmanTransporter) pipeline(ctx context.Context, aduRequests [][]byte, deliver func(i int, aduResponse []byte, err error)) (unanswered []int) {
+	ctx, span := mb.startSpan(ctx, SpanPipeline)
+	var err error
+	defer func() {
+		span.End(err)
+	}()
+
+	if err = mb.connect(ctx); err != nil {
+		for i := range aduRequests {
+			unanswered = append(unanswered, i)
+		}
+		return unanswered
+	}
+	conn := mb.conn
+	// Unblock pending reads and writes as soon as ctx is done.
+	stop := context.AfterFunc(ctx, func() {
+		conn.SetDeadline(time.Unix(1, 0))
+	})
+	defer stop()
+
+	pending := make(map[uint16]int) // Index of the outstanding request by its sequence number key.
+	sent := make([]time.Time, len(aduRequests))
+	var invalid []int // Indexes of the requests with an invalid response.
+	next := 0
+exchange:
+	for next < len(aduRequests) || len(pending) > 0 {
+		for next < len(aduRequests) && len(pending) < mb.PipelineWindow {
+			key := mb.sequenceKey(aduRequests[next])
+			if _, ok := pending[key]; ok {
+				break
+			}
+			if err = conn.SetDeadline(mb.deadline(ctx)); err != nil {
+				break exchange
+			}
+			if err = mb.write(aduRequests[next]); err != nil {
+				break exchange
+			}
+			if mb.logEnabled(ctx, slog.LevelDebug) {
+				mb.log(ctx, slog.LevelDebug, "sent frame", append(frameAttrs(aduRequests[next]), slog.Int("outstanding", len(pending)+1))...)
+			}
+			sent[next] = time.Now()
+			pending[key] = next
+			next++
+		}
+
+		if err = conn.SetDeadline(mb.deadline(ctx)); err != nil {
+			break
+		}
+		var frame []byte
+		if frame, err = mb.read(ctx); err != nil {
+			break
+		}
+		key := mb.sequenceKey(frame)
+		i, ok := pending[key]
+		if !ok || !mb.matches(aduRequests[i], frame) {
+			mb.skip(ctx, frame)
+			continue
+		}
+		delete(pending, key)
+		latency := time.Since(sent[i])
+		if mb.logEnabled(ctx, slog.LevelDebug) {
+			mb.log(ctx, slog.LevelDebug, "received frame", append(frameAttrs(frame), slog.Duration("latency", latency))...)
+		}
+		// Invalid responses are sent again one at a time, so that they are
+		// retried according to the RetryPolicy like in serial mode.
+		if verifyErr := mb.responseChecker().verifyResponse(ctx, aduRequests[i], frame); verifyErr != nil {
+			invalid = append(invalid, i)
+			continue
+		}
+		if mb.Metrics != nil {
+			mb.Metrics.ObserveRequest(requestFunctionCode(aduRequests[i]), OutcomeSuccess, latency)
+		}
+		deliver(i, frame, nil)
+	}
+	if err == nil {
+		return invalid
+	}
+
+	// Late responses must not be mistaken for the responses to the retries.
+	err = contextError(ctx, err)
+	mb.close()
+	if ctx.Err() == nil && errors.Is(err, os.ErrDeadlineExceeded) {
+		mb.pipelineUnsupported = true
+		mb.serialSuccesses = 0
+		mb.log(ctx, slog.LevelWarn, "pipelining not supported, falling back to serial mode", slog.Any("error", err))
+	} else {
+		mb.log(ctx, slog.LevelWarn, "pipelined exchange failed", slog.Any("error", err))
+	}
+	unanswered = invalid
+	for _, i := range pending {
+		unanswered = append(unanswered, i)
+	}
+	slices.Sort(unanswered)
+	for i := next; i < len(aduRequests); i++ {
+		unanswered = append(unanswered, i)
+	}
+	return unanswered
+}
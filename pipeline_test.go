@@ -0,0 +1,201 @@
+package gosolarman
+
+import (
+	"context"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/grid-x/modbus"
+)
+
+// readRequests returns the PDUs of read holding registers requests for the given addresses.
+func readRequests(addresses ...uint16) []*modbus.ProtocolDataUnit {
+	pdus := make([]*modbus.ProtocolDataUnit, len(addresses))
+	for i, address := range addresses {
+		pdus[i] = &modbus.ProtocolDataUnit{
+			FunctionCode: modbus.FuncCodeReadHoldingRegisters,
+			Data:         dataBlock(address, 1),
+		}
+	}
+	return pdus
+}
+
+// waitRegisters waits for the futures and checks that each carries the address it was read from.
+func waitRegisters(t *testing.T, futures []*Future, addresses ...uint16) {
+	t.Helper()
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	for i, future := range futures {
+		response, err := future.Wait(ctx)
+		if err != nil {
+			t.Fatalf("Request %d failed: %v", i, err)
+		}
+		if expected := append([]byte{0x02}, dataBlock(addresses[i])...); string(response.Data) != string(expected) {
+			t.Errorf("Request %d: expected %X, got %X", i, expected, response.Data)
+		}
+	}
+}
+
+// echoAddress answers a read request with the requested address as register value.
+func echoAddress(request []byte) []byte {
+	return responseFrame(request, uint16(request[28])<<8|uint16(request[29]))
+}
+
+func TestSendPipelined(t *testing.T) {
+	client, server := newPipeClient()
+	defer server.Close()
+	client.handler.PipelineWindow = 3
+
+	go func() {
+		reader := newFrameReader(server)
+		var requests [][]byte
+		for range 3 {
+			request, err := reader.ReadFrame()
+			if err != nil {
+				t.Errorf("failed to read request: %v", err)
+				return
+			}
+			requests = append(requests, request)
+		}
+		// All requests are outstanding, answer them in reverse order while
+		// the client sends the next one.
+		written := make(chan struct{})
+		go func() {
+			defer close(written)
+			for i := len(requests) - 1; i >= 0; i-- {
+				server.Write(echoAddress(requests[i]))
+			}
+		}()
+		request, err := reader.ReadFrame()
+		if err != nil {
+			t.Errorf("failed to read request: %v", err)
+			return
+		}
+		<-written
+		server.Write(echoAddress(request))
+	}()
+
+	futures := client.SendPipelinedContext(context.Background(), readRequests(10, 20, 30, 40))
+	waitRegisters(t, futures, 10, 20, 30, 40)
+	if client.handler.pipelineUnsupported {
+		t.Errorf("Expected pipelining to stay enabled")
+	}
+}
+
+func TestSendPipelinedFallback(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Listen failed: %v", err)
+	}
+	defer listener.Close()
+	go func() {
+		received := 0
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			reader := newFrameReader(conn)
+			for {
+				request, err := reader.ReadFrame()
+				if err != nil {
+					break
+				}
+				// Like a logger that drops requests while it is busy, ignore
+				// the second and third request.
+				if received++; received == 2 || received == 3 {
+					continue
+				}
+				conn.Write(echoAddress(request))
+			}
+			conn.Close()
+		}
+	}()
+
+	handler := NewSolarmanClientHandler(listener.Addr().String(), 0x12345678)
+	handler.SlaveID = 0x01
+	handler.Timeout = 100 * time.Millisecond
+	handler.PipelineWindow = 3
+	defer handler.Close()
+	client := NewContextClient(handler)
+
+	futures := client.SendPipelinedContext(context.Background(), readRequests(10, 20, 30))
+	waitRegisters(t, futures, 10, 20, 30)
+	if !handler.pipelineUnsupported {
+		t.Errorf("Expected fallback to serial mode")
+	}
+
+	futures = client.SendPipelinedContext(context.Background(), readRequests(40, 50))
+	waitRegisters(t, futures, 40, 50)
+
+	// The fallback ends after enough successful requests.
+	handler.mu.Lock()
+	handler.serialSuccesses = pipelineProbeInterval - 1
+	handler.mu.Unlock()
+	futures = client.SendPipelinedContext(context.Background(), readRequests(60))
+	waitRegisters(t, futures, 60)
+	handler.mu.Lock()
+	defer handler.mu.Unlock()
+	if handler.pipelineUnsupported {
+		t.Errorf("Expected pipelining to be tried again")
+	}
+}
+
+func TestSendPipelinedRetriesInvalidResponse(t *testing.T) {
+	client, server := newPipeClient()
+	defer server.Close()
+	client.handler.PipelineWindow = 2
+	client.handler.RetryPolicy = &BackoffRetryPolicy{MaxAttempts: 2, RetryProtocol: true}
+	go func() {
+		reader := newFrameReader(server)
+		var requests [][]byte
+		for range 2 {
+			request, err := reader.ReadFrame()
+			if err != nil {
+				return
+			}
+			requests = append(requests, request)
+		}
+		server.Write(echoAddress(requests[0]))
+		server.Write(badCRC(echoAddress(requests[1])))
+		// Like in serial mode, the request is retried after the invalid
+		// response: once more on its own, then within its retry loop.
+		for _, corrupt := range []bool{true, false} {
+			request, err := reader.ReadFrame()
+			if err != nil {
+				return
+			}
+			response := echoAddress(request)
+			if corrupt {
+				response = badCRC(response)
+			}
+			server.Write(response)
+		}
+	}()
+
+	futures := client.SendPipelinedContext(context.Background(), readRequests(10, 20))
+	waitRegisters(t, futures, 10, 20)
+	if client.handler.pipelineUnsupported {
+		t.Errorf("Expected pipelining to stay enabled")
+	}
+}
+
+func TestFutureWait(t *testing.T) {
+	future := newFuture()
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := future.Wait(ctx); err != context.Canceled {
+		t.Errorf("Expected context.Canceled, got %v", err)
+	}
+
+	future.resolve(&modbus.ProtocolDataUnit{FunctionCode: 0x03}, nil)
+	select {
+	case <-future.Done():
+	default:
+		t.Fatalf("Expected future to be done")
+	}
+	if response, err := future.Wait(context.Background()); err != nil || response.FunctionCode != 0x03 {
+		t.Errorf("Expected response, got %v, %v", response, err)
+	}
+}
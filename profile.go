@@ -0,0 +1,88 @@
+package gosolarman
+
+import (
+	"fmt"
+	"strings"
+)
+
+const (
+	// FrameTypeCloud is the frame type of frames exchanged with the Solarman cloud.
+	FrameTypeCloud = 0x00
+
+	// FrameTypeLogger is the frame type of requests addressed to the data logging stick itself.
+	FrameTypeLogger = 0x01
+
+	// FrameTypeInverter is the frame type of requests forwarded to the device behind the data logging stick.
+	FrameTypeInverter = 0x02
+)
+
+// DeviceProfile holds the fields of outgoing request payloads that depend on
+// the data logging stick firmware and the device behind it.
+type DeviceProfile struct {
+	FrameType        byte   // Frame type of outgoing requests (e.g., 0x02 for solar inverter).
+	SensorType       uint16 // Sensor type of outgoing requests (e.g., 0x0000).
+	TotalWorkingTime uint32 // Total working time sent in outgoing requests.
+	PowerOnTime      uint32 // Power on time sent in outgoing requests.
+	OffsetTime       uint32 // Offset time sent in outgoing requests.
+}
+
+var (
+	// DeviceProfileInverter is the profile for the Modbus device on the RS485
+	// port of the data logging stick, usually an inverter. Meters and
+	// batteries on that port are addressed the same way; no device class with
+	// another documented frame type or sensor type is known. Devices that need
+	// other fields require a custom profile.
+	DeviceProfileInverter = DeviceProfile{
+		FrameType:        FrameType,
+		SensorType:       SensorType,
+		TotalWorkingTime: TotalWorkingTime,
+		PowerOnTime:      PowerOnTime,
+		OffsetTime:       OffsetTime,
+	}
+
+	// DeviceProfileLogger is the profile for requests addressed to the data logging stick itself.
+	DeviceProfileLogger = DeviceProfile{
+		FrameType:  FrameTypeLogger,
+		SensorType: SensorType,
+	}
+
+	// DefaultDeviceProfile is the profile used by new handlers and packagers.
+	DefaultDeviceProfile = DeviceProfileInverter
+)
+
+// deviceProfiles maps the names accepted by LookupDeviceProfile to the presets.
+var deviceProfiles = map[string]DeviceProfile{
+	"inverter": DeviceProfileInverter,
+	"logger":   DeviceProfileLogger,
+}
+
+// LookupDeviceProfile returns the preset with the given name (e.g., "inverter").
+//
+// Parameters:
+//   - name: The case-insensitive name of the preset.
+//
+// Returns:
+//   - The device profile.
+//   - An error if there is no preset with the given name.
+func LookupDeviceProfile(name string) (DeviceProfile, error) {
+	profile, ok := deviceProfiles[strings.ToLower(name)]
+	if !ok {
+		return DeviceProfile{}, fmt.Errorf("unknown device profile %q", name)
+	}
+	return profile, nil
+}
+
+// Validate checks that the profile uses a known frame type. The sensor type
+// and the timing fields are not validated, as no constraints on their values
+// are documented.
+//
+// Returns:
+//   - An error if the frame type is unknown.
+func (p DeviceProfile) Validate() error {
+	switch p.FrameType {
+	case FrameTypeCloud, FrameTypeLogger, FrameTypeInverter:
+		return nil
+	default:
+		return fmt.Errorf("unknown frame type 0x%02X", p.FrameType)
+	}
+}
@@ -0,0 +1,72 @@
+package gosolarman
+
+import (
+	"sync"
+	"testing"
+
+	"github.com/grid-x/modbus"
+)
+
+func TestLookupDeviceProfile(t *testing.T) {
+	profile, err := LookupDeviceProfile("Logger")
+	if err != nil {
+		t.Fatalf("LookupDeviceProfile failed: %v", err)
+	}
+	if profile != DeviceProfileLogger {
+		t.Errorf("Expected %+v, got %+v", DeviceProfileLogger, profile)
+	}
+	if _, err := LookupDeviceProfile("toaster"); err == nil {
+		t.Errorf("Expected error for unknown device profile")
+	}
+}
+
+func TestSetDeviceProfile(t *testing.T) {
+	handler := NewSolarmanClientHandler("192.168.1.1:8899", 0x12345678)
+	if handler.FrameType != FrameType || handler.SensorType != SensorType {
+		t.Errorf("Expected default frame type and sensor type, got %+v", handler.DeviceProfile)
+	}
+
+	if err := handler.SetDeviceProfile(DeviceProfile{FrameType: 0x07}); err == nil {
+		t.Errorf("Expected error for unknown frame type")
+	}
+	if err := handler.SetDeviceProfile(DeviceProfile{FrameType: FrameTypeLogger, SensorType: 0x1234, OffsetTime: 0x01020304}); err != nil {
+		t.Fatalf("SetDeviceProfile failed: %v", err)
+	}
+
+	adu, err := handler.Encode(&modbus.ProtocolDataUnit{FunctionCode: 0x03, Data: []byte{0x00, 0x01, 0x00, 0x01}})
+	if err != nil {
+		t.Fatalf("Encode failed: %v", err)
+	}
+	request, err := ParseRequest(adu)
+	if err != nil {
+		t.Fatalf("ParseRequest failed: %v", err)
+	}
+	if request.Payload.FrameType != FrameTypeLogger || request.Payload.SensorType != 0x1234 || request.Payload.OffsetTime != 0x01020304 {
+		t.Errorf("Expected device profile in request payload, got %+v", request.Payload)
+	}
+}
+
+func TestSetDeviceProfileConcurrent(t *testing.T) {
+	handler := NewSolarmanClientHandler("192.168.1.1:8899", 0x12345678)
+	pdu := &modbus.ProtocolDataUnit{FunctionCode: 0x03, Data: []byte{0x00, 0x01, 0x00, 0x01}}
+
+	var wg sync.WaitGroup
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		for i := range 100 {
+			handler.SetSlave(byte(i))
+			handler.SetDeviceProfile(DeviceProfileLogger)
+		}
+	}()
+	go func() {
+		defer wg.Done()
+		for range 100 {
+			if _, err := handler.Encode(pdu); err != nil {
+				t.Errorf("Encode failed: %v", err)
+				return
+			}
+		}
+	}()
+	wg.Wait()
+}
@@ -0,0 +1,134 @@
+// Package promsolarman provides a Prometheus collector for the metrics of
+// gosolarman handlers.
+package promsolarman
+
+import (
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/tlmnb/gosolarman"
+)
+
+// Collector is a prometheus.Collector for the metrics of any number of
+// Solarman client handlers. All metrics are labelled by address and logger serial.
+type Collector struct {
+	requests    *prometheus.CounterVec   // Requests by function code and outcome.
+	latency     *prometheus.HistogramVec // Request latency by function code.
+	reconnects  *prometheus.CounterVec   // Reconnects.
+	frameErrors *prometheus.CounterVec   // Invalid responses by kind.
+	unsolicited *prometheus.CounterVec   // Unsolicited frames by control code.
+}
+
+// NewCollector creates a new collector. Register it with a prometheus.Registerer
+// and attach it to each handler with Attach.
+//
+// Parameters:
+//   - namespace: The namespace of the metric names (e.g., "solarman").
+//
+// Returns:
+//   - A pointer to the created Collector.
+func NewCollector(namespace string) *Collector {
+	labels := []string{"address", "logger_serial"}
+	return &Collector{
+		requests: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "requests_total",
+			Help:      "Number of requests by function code and outcome.",
+		}, append(labels, "function_code", "outcome")),
+		latency: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Name:      "request_duration_seconds",
+			Help:      "Latency of requests including retries.",
+			Buckets:   []float64{0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10},
+		}, append(labels, "function_code")),
+		reconnects: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "reconnects_total",
+			Help:      "Number of connections that replaced a previous one.",
+		}, labels),
+		frameErrors: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "frame_errors_total",
+			Help:      "Number of responses that failed verification or decoding by kind.",
+		}, append(labels, "kind")),
+		unsolicited: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "unsolicited_frames_total",
+			Help:      "Number of frames pushed by the data logging stick by control code.",
+		}, append(labels, "control_code")),
+	}
+}
+
+// Describe implements prometheus.Collector.
+func (c *Collector) Describe(ch chan<- *prometheus.Desc) {
+	c.requests.Describe(ch)
+	c.latency.Describe(ch)
+	c.reconnects.Describe(ch)
+	c.frameErrors.Describe(ch)
+	c.unsolicited.Describe(ch)
+}
+
+// Collect implements prometheus.Collector.
+func (c *Collector) Collect(ch chan<- prometheus.Metric) {
+	c.requests.Collect(ch)
+	c.latency.Collect(ch)
+	c.reconnects.Collect(ch)
+	c.frameErrors.Collect(ch)
+	c.unsolicited.Collect(ch)
+}
+
+// Attach sets the Metrics of handler to record into the collector, labelled
+// with the address and logger serial of the handler.
+// Attach must be called before the handler is used.
+//
+// Parameters:
+//   - handler: The Solarman client handler to collect metrics from.
+func (c *Collector) Attach(handler *gosolarman.SolarmanClientHandler) {
+	handler.Metrics = c.Metrics(handler.Address, handler.LoggerSerial)
+}
+
+// Metrics returns a gosolarman.Metrics that records into the collector with
+// the given labels.
+//
+// Parameters:
+//   - address: The address of the Solarman device.
+//   - loggerSerial: The serial number of the data logging stick.
+//
+// Returns:
+//   - The metrics hook for a handler.
+func (c *Collector) Metrics(address string, loggerSerial uint32) gosolarman.Metrics {
+	return &handlerMetrics{
+		collector: c,
+		labels:    prometheus.Labels{"address": address, "logger_serial": strconv.FormatUint(uint64(loggerSerial), 10)},
+	}
+}
+
+// handlerMetrics implements gosolarman.Metrics for a single handler.
+type handlerMetrics struct {
+	collector *Collector        // Collector to record into.
+	labels    prometheus.Labels // Address and logger serial labels.
+}
+
+// ObserveRequest implements gosolarman.Metrics.
+func (m *handlerMetrics) ObserveRequest(functionCode byte, outcome string, latency time.Duration) {
+	code := fmt.Sprintf("0x%02X", functionCode)
+	m.collector.requests.MustCurryWith(m.labels).WithLabelValues(code, outcome).Inc()
+	m.collector.latency.MustCurryWith(m.labels).WithLabelValues(code).Observe(latency.Seconds())
+}
+
+// ObserveReconnect implements gosolarman.Metrics.
+func (m *handlerMetrics) ObserveReconnect() {
+	m.collector.reconnects.With(m.labels).Inc()
+}
+
+// ObserveFrameError implements gosolarman.Metrics.
+func (m *handlerMetrics) ObserveFrameError(kind string) {
+	m.collector.frameErrors.MustCurryWith(m.labels).WithLabelValues(kind).Inc()
+}
+
+// ObserveUnsolicitedFrame implements gosolarman.Metrics.
+func (m *handlerMetrics) ObserveUnsolicitedFrame(controlCode uint16) {
+	m.collector.unsolicited.MustCurryWith(m.labels).WithLabelValues(fmt.Sprintf("0x%04X", controlCode)).Inc()
+}
@@ -0,0 +1,50 @@
+package promsolarman
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/tlmnb/gosolarman"
+)
+
+func TestCollector(t *testing.T) {
+	collector := NewCollector("solarman")
+	registry := prometheus.NewPedanticRegistry()
+	registry.MustRegister(collector)
+
+	handler := gosolarman.NewSolarmanClientHandler("192.168.1.1:8899", 1234567891)
+	collector.Attach(handler)
+
+	handler.Metrics.ObserveRequest(0x03, gosolarman.OutcomeSuccess, 200*time.Millisecond)
+	handler.Metrics.ObserveRequest(0x03, "transient", time.Second)
+	handler.Metrics.ObserveReconnect()
+	handler.Metrics.ObserveFrameError("checksum")
+	handler.Metrics.ObserveUnsolicitedFrame(gosolarman.ControlCodeHeartbeat)
+
+	expected := `
+# HELP solarman_requests_total Number of requests by function code and outcome.
+# TYPE solarman_requests_total counter
+solarman_requests_total{address="192.168.1.1:8899",function_code="0x03",logger_serial="1234567891",outcome="success"} 1
+solarman_requests_total{address="192.168.1.1:8899",function_code="0x03",logger_serial="1234567891",outcome="transient"} 1
+# HELP solarman_reconnects_total Number of connections that replaced a previous one.
+# TYPE solarman_reconnects_total counter
+solarman_reconnects_total{address="192.168.1.1:8899",logger_serial="1234567891"} 1
+# HELP solarman_frame_errors_total Number of responses that failed verification or decoding by kind.
+# TYPE solarman_frame_errors_total counter
+solarman_frame_errors_total{address="192.168.1.1:8899",kind="checksum",logger_serial="1234567891"} 1
+# HELP solarman_unsolicited_frames_total Number of frames pushed by the data logging stick by control code.
+# TYPE solarman_unsolicited_frames_total counter
+solarman_unsolicited_frames_total{address="192.168.1.1:8899",control_code="0x4710",logger_serial="1234567891"} 1
+`
+	err := testutil.GatherAndCompare(registry, strings.NewReader(expected),
+		"solarman_requests_total", "solarman_reconnects_total", "solarman_frame_errors_total", "solarman_unsolicited_frames_total")
+	if err != nil {
+		t.Error(err)
+	}
+	if count := testutil.CollectAndCount(collector, "solarman_request_duration_seconds"); count != 1 {
+		t.Errorf("Expected 1 latency histogram, got %d", count)
+	}
+}
@@ -0,0 +1,148 @@
+package gosolarman
+
+import (
+	"context"
+	"encoding/binary"
+	"log/slog"
+	"net"
+)
+
+// Proxy accepts Solarman V5 request frames from many clients and forwards
+// them one at a time over the single upstream connection of a handler, so
+// that several tools can share a data logging stick that only allows one
+// TCP client. The sequence number of every request is replaced with one of
+// the handler, and restored in the response sent back to the client.
+type Proxy struct {
+	handler *SolarmanClientHandler // Upstream connection to the data logging stick.
+	server  *server
+}
+
+// NewProxy creates a new proxy on top of a Solarman client handler.
+//
+// Parameters:
+//   - handler: The handler that forwards the requests to the data logging stick.
+//
+// Returns:
+//   - A pointer to the created Proxy.
+func NewProxy(handler *SolarmanClientHandler) *Proxy {
+	return &Proxy{
+		handler: handler,
+		server:  newServer(),
+	}
+}
+
+// ListenAndServe listens on a TCP address and serves clients until the proxy is closed.
+//
+// Parameters:
+//   - address: The address to listen on (e.g., ":8899").
+//
+// Returns:
+//   - An error if listening fails, or net.ErrClosed after Close.
+func (p *Proxy) ListenAndServe(address string) error {
+	listener, err := net.Listen("tcp", address)
+	if err != nil {
+		return err
+	}
+	return p.Serve(listener)
+}
+
+// Serve accepts clients on listener until the proxy is closed.
+//
+// Parameters:
+//   - listener: The listener to accept clients on. It is closed by Serve.
+//
+// Returns:
+//   - An error if accepting fails, or net.ErrClosed after Close.
+func (p *Proxy) Serve(listener net.Listener) error {
+	return p.server.serve(listener, p.serveConn)
+}
+
+// Close stops accepting clients, disconnects all clients and aborts the
+// request in progress. The handler is not closed.
+//
+// Returns:
+//   - An error if closing a listener fails.
+func (p *Proxy) Close() error {
+	return p.server.close()
+}
+
+// serveConn forwards the requests of one client until it disconnects. If a
+// request cannot be forwarded, the client is disconnected rather than left
+// waiting for a response that never arrives.
+//
+// Parameters:
+//   - ctx: The context of the proxy, cancelled on Close.
+//   - conn: The connection of the client.
+func (p *Proxy) serveConn(ctx context.Context, conn net.Conn) {
+	client := slog.String("client", conn.RemoteAddr().String())
+	p.handler.log(ctx, slog.LevelInfo, "client connected", client)
+	defer p.handler.log(ctx, slog.LevelInfo, "client disconnected", client)
+
+	reader := newFrameReader(conn)
+	for {
+		request, err := reader.ReadFrame()
+		if err != nil {
+			return
+		}
+		response, err := p.forward(ctx, request)
+		if err != nil {
+			if ctx.Err() != nil {
+				return
+			}
+			p.handler.log(ctx, slog.LevelWarn, "forwarding failed, disconnecting client", client, slog.Any("error", err))
+			return
+		}
+		if _, err := conn.Write(response); err != nil {
+			return
+		}
+	}
+}
+
+// forward sends the request frame of a client upstream with a sequence
+// number of the handler and returns the response with the sequence number
+// of the client.
+//
+// Parameters:
+//   - ctx: The context controlling the exchange.
+//   - request: The request frame of the client.
+//
+// Returns:
+//   - response: The response frame for the client.
+//   - err: An error if the request is invalid or the exchange fails.
+func (p *Proxy) forward(ctx context.Context, request []byte) (response []byte, err error) {
+	if _, err = ParseHeader(request); err != nil {
+		return nil, err
+	}
+	if controlCode := binary.LittleEndian.Uint16(request[3:5]); controlCode != ControlCodeRequest {
+		return nil, newFrameError(ErrControlCode, ControlCodeRequest, uint32(controlCode), request)
+	}
+	if checksum := CheckSum(request[1 : len(request)-2]); request[len(request)-2] != checksum {
+		return nil, newFrameError(ErrChecksum, uint32(checksum), uint32(request[len(request)-2]), request)
+	}
+
+	sequence := binary.LittleEndian.Uint16(request[5:7])
+	response, err = p.handler.SendContext(ctx, setSequence(request, p.handler.nextSequence()))
+	if err != nil {
+		return nil, err
+	}
+	if len(response) < headerLength+trailerLength {
+		return nil, newFrameError(ErrShortFrame, headerLength+trailerLength, uint32(len(response)), response)
+	}
+	return setSequence(response, sequence), nil
+}
+
+// setSequence returns a copy of frame with the given sequence number and an
+// updated checksum.
+//
+// Parameters:
+//   - frame: The frame to copy.
+//   - sequence: The sequence number to set.
+//
+// Returns:
+//   - The modified copy of frame.
+func setSequence(frame []byte, sequence uint16) []byte {
+	frame = append([]byte(nil), frame...)
+	binary.LittleEndian.PutUint16(frame[5:7], sequence)
+	frame[len(frame)-2] = CheckSum(frame[1 : len(frame)-2])
+	return frame
+}
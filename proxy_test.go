@@ -0,0 +1,159 @@
+package gosolarman
+
+import (
+	"context"
+	"errors"
+	"net"
+	"os"
+	"sync"
+	"sync/atomic"
+	"testing"
+)
+
+func TestProxy(t *testing.T) {
+	upstream, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Listen failed: %v", err)
+	}
+	defer upstream.Close()
+	var connections atomic.Int32
+	go func() {
+		for {
+			conn, err := upstream.Accept()
+			if err != nil {
+				return
+			}
+			connections.Add(1)
+			go func() {
+				defer conn.Close()
+				reader := newFrameReader(conn)
+				for {
+					request, err := reader.ReadFrame()
+					if err != nil {
+						return
+					}
+					// Like most data logging sticks, replace the second
+					// sequence number byte with a frame counter.
+					response := echoAddress(request)
+					response[6]++
+					response[len(response)-2]++
+					conn.Write(response)
+				}
+			}()
+		}
+	}()
+
+	upstreamHandler := NewSolarmanClientHandler(upstream.Addr().String(), 0x12345678)
+	upstreamHandler.LooseSequence = true
+	proxy := NewProxy(upstreamHandler)
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Listen failed: %v", err)
+	}
+	served := make(chan error, 1)
+	go func() {
+		served <- proxy.Serve(listener)
+	}()
+
+	var wg sync.WaitGroup
+	for i := range 3 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			handler := NewSolarmanClientHandler(listener.Addr().String(), 0x12345678)
+			handler.SlaveID = 0x01
+			handler.SetSequence(0x0101) // Every client uses the same sequence numbers.
+			defer handler.Close()
+			client := NewContextClient(handler)
+			for j := range 5 {
+				address := uint16(100*i + j)
+				results, err := client.ReadHoldingRegistersContext(context.Background(), address, 1)
+				if err != nil {
+					t.Errorf("Client %d: ReadHoldingRegistersContext failed: %v", i, err)
+					return
+				}
+				if expected := dataBlock(address); string(results) != string(expected) {
+					t.Errorf("Client %d: expected %X, got %X", i, expected, results)
+				}
+			}
+		}()
+	}
+	wg.Wait()
+
+	if err := proxy.Close(); err != nil {
+		t.Errorf("Close failed: %v", err)
+	}
+	if err := <-served; !errors.Is(err, net.ErrClosed) {
+		t.Errorf("Expected net.ErrClosed, got %v", err)
+	}
+	if n := connections.Load(); n != 1 {
+		t.Errorf("Expected 1 upstream connection, got %d", n)
+	}
+}
+
+func TestProxyForwardFailure(t *testing.T) {
+	// The upstream closes every connection without answering.
+	upstream, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Listen failed: %v", err)
+	}
+	defer upstream.Close()
+	go func() {
+		for {
+			conn, err := upstream.Accept()
+			if err != nil {
+				return
+			}
+			conn.Close()
+		}
+	}()
+
+	proxy := NewProxy(NewSolarmanClientHandler(upstream.Addr().String(), 0x12345678))
+	defer proxy.Close()
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Listen failed: %v", err)
+	}
+	go proxy.Serve(listener)
+
+	handler := NewSolarmanClientHandler(listener.Addr().String(), 0x12345678)
+	handler.RetryPolicy = &BackoffRetryPolicy{MaxAttempts: 1}
+	defer handler.Close()
+	client := NewContextClient(handler)
+
+	// The proxy disconnects the client instead of leaving it waiting for the timeout.
+	_, err = client.ReadHoldingRegistersContext(context.Background(), 0x0010, 1)
+	if err == nil || errors.Is(err, os.ErrDeadlineExceeded) {
+		t.Errorf("Expected the client to be disconnected, got %v", err)
+	}
+}
+
+func TestProxyForwardInvalid(t *testing.T) {
+	proxy := NewProxy(NewSolarmanClientHandler("192.168.1.1:8899", 0x12345678))
+	request := []byte{0xA5, 0x00, 0x00, 0x10, 0x47, 0x01, 0x00, 0x78, 0x56, 0x34, 0x12, 0x00, 0x15}
+	request[11] = CheckSum(request[1:11])
+
+	if _, err := proxy.forward(context.Background(), request); !errors.Is(err, ErrControlCode) {
+		t.Errorf("Expected ErrControlCode, got %v", err)
+	}
+	request[4] = 0x45
+	if _, err := proxy.forward(context.Background(), request); !errors.Is(err, ErrChecksum) {
+		t.Errorf("Expected ErrChecksum, got %v", err)
+	}
+}
+
+func TestSetSequence(t *testing.T) {
+	frame := []byte{0xA5, 0x00, 0x00, 0x10, 0x45, 0x01, 0x00, 0x78, 0x56, 0x34, 0x12, 0x00, 0x15}
+	frame[11] = CheckSum(frame[1:11])
+
+	modified := setSequence(frame, 0xBEEF)
+	if modified[5] != 0xEF || modified[6] != 0xBE {
+		t.Errorf("Expected sequence EFBE, got %X", modified[5:7])
+	}
+	if modified[11] != CheckSum(modified[1:11]) {
+		t.Errorf("Expected checksum to be updated")
+	}
+	if frame[5] != 0x01 {
+		t.Errorf("Expected original frame to be unchanged")
+	}
+}
@@ -0,0 +1,400 @@
+package gosolarman
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"sync"
+	"syscall"
+	"time"
+)
+
+// Exchange is a recorded request and its response. In JSON, the frames are
+// encoded in hex, so that a recording can be read and edited by hand.
+type Exchange struct {
+	Time     time.Time     // Time the request was sent.
+	Latency  time.Duration // Time until the response was received or the exchange failed.
+	Request  []byte        // Request frame.
+	Response []byte        // Response frame, or nil if the exchange failed.
+	Error    string        // Error of the failed exchange, or an empty string.
+
+	// ErrorKind identifies the cause of Error (e.g., "timeout", "eof" or
+	// "checksum"), so that a replayed error is classified like the original
+	// by ClassifyError. It is empty if the cause is unknown.
+	ErrorKind string
+}
+
+// exchangeJSON is the JSON encoding of an Exchange.
+type exchangeJSON struct {
+	Time     time.Time `json:"time"`
+	Latency  string    `json:"latency"`
+	Request  string    `json:"request"`
+	Response string    `json:"response,omitempty"`
+	Error    string    `json:"error,omitempty"`
+	Kind     string    `json:"error_kind,omitempty"`
+}
+
+// MarshalJSON encodes the exchange with hex encoded frames.
+//
+// Returns:
+//   - The JSON encoding of the exchange.
+//   - An error if the encoding fails.
+func (e Exchange) MarshalJSON() ([]byte, error) {
+	return json.Marshal(exchangeJSON{
+		Time:     e.Time,
+		Latency:  e.Latency.String(),
+		Request:  hex.EncodeToString(e.Request),
+		Response: hex.EncodeToString(e.Response),
+		Error:    e.Error,
+		Kind:     e.ErrorKind,
+	})
+}
+
+// UnmarshalJSON decodes an exchange with hex encoded frames.
+//
+// Parameters:
+//   - data: The JSON encoding of the exchange.
+//
+// Returns:
+//   - An error if the JSON, the latency or a frame is invalid.
+func (e *Exchange) UnmarshalJSON(data []byte) error {
+	var encoded exchangeJSON
+	if err := json.Unmarshal(data, &encoded); err != nil {
+		return err
+	}
+	exchange := Exchange{Time: encoded.Time, Error: encoded.Error, ErrorKind: encoded.Kind}
+	var err error
+	if encoded.Latency != "" {
+		if exchange.Latency, err = time.ParseDuration(encoded.Latency); err != nil {
+			return fmt.Errorf("invalid latency: %w", err)
+		}
+	}
+	if exchange.Request, err = hex.DecodeString(encoded.Request); err != nil {
+		return fmt.Errorf("invalid request: %w", err)
+	}
+	if encoded.Response != "" {
+		if exchange.Response, err = hex.DecodeString(encoded.Response); err != nil {
+			return fmt.Errorf("invalid response: %w", err)
+		}
+	}
+	*e = exchange
+	return nil
+}
+
+// ReadExchanges reads a recording written by a RecordingTransport.
+//
+// Parameters:
+//   - r: The reader of the recording, with one JSON encoded exchange per line.
+//
+// Returns:
+//   - The recorded exchanges in order.
+//   - An error if reading fails or a line is invalid.
+func ReadExchanges(r io.Reader) ([]Exchange, error) {
+	var exchanges []Exchange
+	scanner := bufio.NewScanner(r)
+	for line := 1; scanner.Scan(); line++ {
+		if len(bytes.TrimSpace(scanner.Bytes())) == 0 {
+			continue
+		}
+		var exchange Exchange
+		if err := json.Unmarshal(scanner.Bytes(), &exchange); err != nil {
+			return nil, fmt.Errorf("line %d: %w", line, err)
+		}
+		exchanges = append(exchanges, exchange)
+	}
+	return exchanges, scanner.Err()
+}
+
+// RecordingTransport is a Transport that records every exchange of another
+// Transport as JSON Lines, e.g. to turn the responses of a real data logging
+// stick into a regression test with ReplayTransport. It is safe for concurrent use.
+type RecordingTransport struct {
+	Transport // The transport that exchanges the frames.
+
+	mu      sync.Mutex
+	encoder *json.Encoder // Encoder of the recording.
+	err     error         // First error writing the recording.
+}
+
+var _ Transport = (*RecordingTransport)(nil)
+
+// NewRecordingTransport creates a new transport that records the exchanges of another.
+//
+// Parameters:
+//   - transport: The transport that exchanges the frames (e.g., a *SolarmanClientHandler).
+//   - w: The writer of the recording.
+//
+// Returns:
+//   - A pointer to the created RecordingTransport.
+func NewRecordingTransport(transport Transport, w io.Writer) *RecordingTransport {
+	return &RecordingTransport{
+		Transport: transport,
+		encoder:   json.NewEncoder(w),
+	}
+}
+
+// Send sends a request frame and records the exchange.
+//
+// Parameters:
+//   - aduRequest: The request frame.
+//
+// Returns:
+//   - aduResponse: The response frame.
+//   - err: An error if the exchange fails.
+func (t *RecordingTransport) Send(aduRequest []byte) (aduResponse []byte, err error) {
+	return t.SendContext(context.Background(), aduRequest)
+}
+
+// SendContext sends a request frame and records the exchange. Failed
+// exchanges are recorded with their error. Failing to write the recording
+// does not fail the exchange; it is reported by Err.
+//
+// Parameters:
+//   - ctx: The context controlling the exchange.
+//   - aduRequest: The request frame.
+//
+// Returns:
+//   - aduResponse: The response frame.
+//   - err: An error if the exchange fails.
+func (t *RecordingTransport) SendContext(ctx context.Context, aduRequest []byte) (aduResponse []byte, err error) {
+	start := time.Now()
+	aduResponse, err = t.Transport.SendContext(ctx, aduRequest)
+	exchange := Exchange{
+		Time:     start,
+		Latency:  time.Since(start),
+		Request:  aduRequest,
+		Response: aduResponse,
+	}
+	if err != nil {
+		exchange.Error = err.Error()
+		exchange.ErrorKind = exchangeErrorKind(err)
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if writeErr := t.encoder.Encode(exchange); writeErr != nil && t.err == nil {
+		t.err = writeErr
+	}
+	return aduResponse, err
+}
+
+// Err returns the first error writing the recording.
+//
+// Returns:
+//   - The error, or nil if all exchanges were recorded.
+func (t *RecordingTransport) Err() error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.err
+}
+
+// ReplayTransport is a Transport that answers requests with the responses of
+// a recording instead of a data logging stick. Requests must arrive in the
+// recorded order and carry the same Modbus RTU frame; the sequence numbers
+// of the responses are rewritten to match the requests. Recorded failures
+// are replayed as errors with the recorded message that wrap an error of the
+// recorded kind, e.g. os.ErrDeadlineExceeded for a timeout, so that they are
+// retried like the original. It is safe for concurrent use.
+type ReplayTransport struct {
+	mu        sync.Mutex
+	exchanges []Exchange // Recorded exchanges.
+	next      int        // Index of the next exchange to replay.
+}
+
+var _ Transport = (*ReplayTransport)(nil)
+
+// NewReplayTransport creates a new transport that replays recorded exchanges.
+//
+// Parameters:
+//   - exchanges: The recorded exchanges in order (e.g., from ReadExchanges).
+//
+// Returns:
+//   - A pointer to the created ReplayTransport.
+func NewReplayTransport(exchanges []Exchange) *ReplayTransport {
+	return &ReplayTransport{exchanges: exchanges}
+}
+
+// Send answers a request frame with the next recorded response.
+//
+// Parameters:
+//   - aduRequest: The request frame.
+//
+// Returns:
+//   - aduResponse: The recorded response frame.
+//   - err: The recorded error, ErrReplayMismatch or ErrReplayExhausted.
+func (t *ReplayTransport) Send(aduRequest []byte) (aduResponse []byte, err error) {
+	return t.SendContext(context.Background(), aduRequest)
+}
+
+// SendContext answers a request frame with the next recorded response.
+//
+// Parameters:
+//   - ctx: The context controlling the exchange.
+//   - aduRequest: The request frame.
+//
+// Returns:
+//   - aduResponse: The recorded response frame.
+//   - err: The recorded error, ErrReplayMismatch, ErrReplayExhausted or the context error.
+func (t *ReplayTransport) SendContext(ctx context.Context, aduRequest []byte) (aduResponse []byte, err error) {
+	if err = ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.next >= len(t.exchanges) {
+		return nil, ErrReplayExhausted
+	}
+	index := t.next
+	exchange := t.exchanges[index]
+	if request := rtuFrame(aduRequest); request == nil || !bytes.Equal(request, rtuFrame(exchange.Request)) {
+		return nil, fmt.Errorf("exchange %d: %w: expected %X, got %X", index, ErrReplayMismatch, exchange.Request, aduRequest)
+	}
+	t.next++
+
+	if exchange.Error != "" {
+		return nil, replayedError(exchange)
+	}
+	if len(exchange.Response) < headerLength+trailerLength {
+		return nil, fmt.Errorf("exchange %d: recorded response too short", index)
+	}
+	return setSequence(exchange.Response, binary.LittleEndian.Uint16(aduRequest[5:7])), nil
+}
+
+// Remaining returns the number of recorded exchanges that have not been replayed.
+//
+// Returns:
+//   - The number of remaining exchanges.
+func (t *ReplayTransport) Remaining() int {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return len(t.exchanges) - t.next
+}
+
+// Connect does nothing, as there is no connection to establish.
+//
+// Returns:
+//   - Always nil.
+func (t *ReplayTransport) Connect() error {
+	return nil
+}
+
+// ConnectContext does nothing, as there is no connection to establish.
+//
+// Parameters:
+//   - ctx: The context controlling the connection.
+//
+// Returns:
+//   - Always nil.
+func (t *ReplayTransport) ConnectContext(ctx context.Context) error {
+	return nil
+}
+
+// Close does nothing, as there is no connection to close.
+//
+// Returns:
+//   - Always nil.
+func (t *ReplayTransport) Close() error {
+	return nil
+}
+
+// rtuFrame returns the Modbus RTU frame of a request frame.
+//
+// Parameters:
+//   - frame: The request frame.
+//
+// Returns:
+//   - The Modbus RTU frame including its CRC, or nil if the frame is too short.
+func rtuFrame(frame []byte) []byte {
+	if len(frame) < headerLength+requestPayloadLength+trailerLength {
+		return nil
+	}
+	return frame[headerLength+requestPayloadLength : len(frame)-trailerLength]
+}
+
+// transportErrorKinds maps the errors of failed exchanges that ClassifyError
+// distinguishes to the kinds recorded in an Exchange.
+var transportErrorKinds = []struct {
+	err  error
+	kind string
+}{
+	{context.Canceled, "canceled"},
+	{context.DeadlineExceeded, "deadline"},
+	{os.ErrDeadlineExceeded, "timeout"},
+	{io.EOF, "eof"},
+	{io.ErrUnexpectedEOF, "unexpected_eof"},
+	{net.ErrClosed, "closed"},
+	{syscall.EPIPE, "broken_pipe"},
+	{syscall.ECONNRESET, "reset"},
+	{syscall.ECONNABORTED, "aborted"},
+	{syscall.ECONNREFUSED, "refused"},
+}
+
+// exchangeErrorKind returns the kind of the error of a failed exchange as
+// recorded in an Exchange.
+//
+// Parameters:
+//   - err: The error of the exchange.
+//
+// Returns:
+//   - The kind of a transport error, the FrameErrorKind of a FrameError, or
+//     an empty string if the cause is unknown.
+func exchangeErrorKind(err error) string {
+	for _, k := range transportErrorKinds {
+		if errors.Is(err, k.err) {
+			return k.kind
+		}
+	}
+	var frameErr *FrameError
+	if errors.As(err, &frameErr) {
+		return FrameErrorKind(err)
+	}
+	return ""
+}
+
+// recordedError is a recorded error of a failed exchange. It reports the
+// recorded message and wraps an error of the recorded kind.
+type recordedError struct {
+	message string // Recorded message.
+	err     error  // Error of the recorded kind.
+}
+
+// Error returns the recorded message.
+func (e *recordedError) Error() string {
+	return e.message
+}
+
+// Unwrap returns the error of the recorded kind.
+func (e *recordedError) Unwrap() error {
+	return e.err
+}
+
+// replayedError rebuilds the error of a failed exchange.
+//
+// Parameters:
+//   - exchange: The recorded exchange.
+//
+// Returns:
+//   - An error with the recorded message that wraps an error of the recorded
+//     kind, or a plain error if the kind is unknown.
+func replayedError(exchange Exchange) error {
+	for _, k := range transportErrorKinds {
+		if k.kind == exchange.ErrorKind {
+			return &recordedError{message: exchange.Error, err: k.err}
+		}
+	}
+	for _, k := range frameErrorKinds {
+		if k.kind == exchange.ErrorKind {
+			return &recordedError{message: exchange.Error, err: &FrameError{Err: k.err}}
+		}
+	}
+	return errors.New(exchange.Error)
+}
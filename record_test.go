@@ -0,0 +1,170 @@
+package gosolarman
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"net"
+	"os"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+)
+
+func TestRecordAndReplay(t *testing.T) {
+	client, server := net.Pipe()
+	defer server.Close()
+	go func() {
+		respond(t, server, 0x002A)
+		respond(t, server, 0x0001, 0x0002)
+	}()
+
+	handler := NewSolarmanClientHandler("pipe", 0x12345678)
+	handler.Dialer = DialerFunc(func(ctx context.Context, network, address string) (net.Conn, error) {
+		return client, nil
+	})
+	defer handler.Close()
+
+	var recording bytes.Buffer
+	recorder := NewRecordingTransport(handler, &recording)
+	modbusClient := NewTransportClient(recorder, 0x12345678, 0x01)
+	if _, err := modbusClient.ReadHoldingRegisters(0x0010, 1); err != nil {
+		t.Fatalf("ReadHoldingRegisters failed: %v", err)
+	}
+	if _, err := modbusClient.ReadInputRegisters(0x0020, 2); err != nil {
+		t.Fatalf("ReadInputRegisters failed: %v", err)
+	}
+	if err := recorder.Err(); err != nil {
+		t.Fatalf("Recording failed: %v", err)
+	}
+
+	exchanges, err := ReadExchanges(&recording)
+	if err != nil {
+		t.Fatalf("ReadExchanges failed: %v", err)
+	}
+	if len(exchanges) != 2 {
+		t.Fatalf("Expected 2 exchanges, got %d", len(exchanges))
+	}
+	if exchanges[0].Time.IsZero() || exchanges[0].Response == nil || exchanges[0].Error != "" {
+		t.Errorf("Expected a successful exchange with a time, got %+v", exchanges[0])
+	}
+
+	replay := NewReplayTransport(exchanges)
+	modbusClient = NewTransportClient(replay, 0x12345678, 0x01)
+	results, err := modbusClient.ReadHoldingRegisters(0x0010, 1)
+	if err != nil {
+		t.Fatalf("Replayed ReadHoldingRegisters failed: %v", err)
+	}
+	if expected := []byte{0x00, 0x2A}; !bytes.Equal(results, expected) {
+		t.Errorf("Expected %X, got %X", expected, results)
+	}
+	if _, err := modbusClient.ReadInputRegisters(0x0021, 2); !errors.Is(err, ErrReplayMismatch) {
+		t.Errorf("Expected ErrReplayMismatch, got %v", err)
+	}
+	// The mismatched request advanced the sequence number beyond the recording.
+	results, err = modbusClient.ReadInputRegisters(0x0020, 2)
+	if err != nil {
+		t.Fatalf("Replayed ReadInputRegisters failed: %v", err)
+	}
+	if expected := []byte{0x00, 0x01, 0x00, 0x02}; !bytes.Equal(results, expected) {
+		t.Errorf("Expected %X, got %X", expected, results)
+	}
+	if _, err := modbusClient.ReadInputRegisters(0x0020, 2); !errors.Is(err, ErrReplayExhausted) {
+		t.Errorf("Expected ErrReplayExhausted, got %v", err)
+	}
+	if replay.Remaining() != 0 {
+		t.Errorf("Expected no remaining exchanges, got %d", replay.Remaining())
+	}
+}
+
+func TestReplayRecordedError(t *testing.T) {
+	recording := `{"time":"2026-01-02T03:04:05Z","latency":"5s","request":"a51700104500007856341202000000000000000000000000000001030010000185cfeb15","error":"failed to read from \"192.168.1.10:8899\": i/o timeout"}`
+	exchanges, err := ReadExchanges(strings.NewReader(recording))
+	if err != nil {
+		t.Fatalf("ReadExchanges failed: %v", err)
+	}
+	if exchanges[0].Latency != 5*time.Second {
+		t.Errorf("Expected latency 5s, got %v", exchanges[0].Latency)
+	}
+
+	client := NewTransportClient(NewReplayTransport(exchanges), 0x12345678, 0x01)
+	if _, err := client.ReadHoldingRegisters(0x0010, 1); err == nil || !strings.Contains(err.Error(), "i/o timeout") {
+		t.Errorf("Expected the recorded error, got %v", err)
+	}
+}
+
+func TestReplayRecordedTimeout(t *testing.T) {
+	client, server := net.Pipe()
+	defer server.Close()
+	go newFrameReader(server).ReadFrame() // Accept the request but never answer.
+
+	handler := NewSolarmanClientHandler("pipe", 0x12345678)
+	handler.Timeout = 50 * time.Millisecond
+	handler.RetryPolicy = &BackoffRetryPolicy{MaxAttempts: 1}
+	handler.Dialer = DialerFunc(func(ctx context.Context, network, address string) (net.Conn, error) {
+		return client, nil
+	})
+	defer handler.Close()
+
+	var recording bytes.Buffer
+	recorder := NewRecordingTransport(handler, &recording)
+	_, recordedErr := NewTransportClient(recorder, 0x12345678, 0x01).ReadHoldingRegisters(0x0010, 1)
+	if ClassifyError(recordedErr) != ErrorClassTransient {
+		t.Fatalf("Expected a transient error, got %v", recordedErr)
+	}
+
+	exchanges, err := ReadExchanges(&recording)
+	if err != nil {
+		t.Fatalf("ReadExchanges failed: %v", err)
+	}
+	if exchanges[0].ErrorKind != "timeout" {
+		t.Errorf("Expected error kind timeout, got %q", exchanges[0].ErrorKind)
+	}
+	_, err = NewTransportClient(NewReplayTransport(exchanges), 0x12345678, 0x01).ReadHoldingRegisters(0x0010, 1)
+	if !errors.Is(err, os.ErrDeadlineExceeded) || ClassifyError(err) != ErrorClassTransient {
+		t.Errorf("Expected a transient timeout, got %v", err)
+	}
+	if err == nil || err.Error() != recordedErr.Error() {
+		t.Errorf("Expected the recorded message %q, got %v", recordedErr, err)
+	}
+}
+
+func TestReplayErrorKinds(t *testing.T) {
+	tests := []struct {
+		kind     string
+		expected error
+		class    ErrorClass
+	}{
+		{"eof", io.EOF, ErrorClassTransient},
+		{"reset", syscall.ECONNRESET, ErrorClassTransient},
+		{"checksum", ErrChecksum, ErrorClassProtocol},
+		{"", nil, ErrorClassFatal},
+	}
+	for _, test := range tests {
+		t.Run(test.kind, func(t *testing.T) {
+			exchange := Exchange{
+				Request:   []byte{0xA5, 0x17, 0x00, 0x10, 0x45, 0x00, 0x00, 0x78, 0x56, 0x34, 0x12, 0x02, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x01, 0x03, 0x00, 0x10, 0x00, 0x01, 0x85, 0xCF, 0xEB, 0x15},
+				Error:     "recorded failure",
+				ErrorKind: test.kind,
+			}
+			_, err := NewReplayTransport([]Exchange{exchange}).Send(exchange.Request)
+			if err == nil || err.Error() != "recorded failure" {
+				t.Errorf("Expected the recorded message, got %v", err)
+			}
+			if test.expected != nil && !errors.Is(err, test.expected) {
+				t.Errorf("Expected %v, got %v", test.expected, err)
+			}
+			if class := ClassifyError(err); class != test.class {
+				t.Errorf("Expected class %s, got %s", test.class, class)
+			}
+		})
+	}
+}
+
+func TestReadExchangesInvalid(t *testing.T) {
+	if _, err := ReadExchanges(strings.NewReader("{\"request\":\"zz\"}\n")); err == nil || !strings.Contains(err.Error(), "line 1") {
+		t.Errorf("Expected an error for line 1, got %v", err)
+	}
+}
@@ -0,0 +1,163 @@
+package gosolarman
+
+import (
+	"context"
+	"errors"
+	"io"
+	"math"
+	"math/rand/v2"
+	"net"
+	"os"
+	"syscall"
+	"time"
+)
+
+// ErrorClass classifies the errors of a failed exchange.
+type ErrorClass int
+
+const (
+	// ErrorClassFatal denotes errors that are returned to the caller without retrying.
+	ErrorClassFatal ErrorClass = iota
+
+	// ErrorClassTransient denotes connection errors (e.g., reset, EOF, timeout)
+	// that may succeed on a new attempt.
+	ErrorClassTransient
+
+	// ErrorClassProtocol denotes invalid or unexpected frames that may succeed
+	// when the request is repeated.
+	ErrorClassProtocol
+)
+
+// String returns the name of the error class.
+func (c ErrorClass) String() string {
+	switch c {
+	case ErrorClassFatal:
+		return "fatal"
+	case ErrorClassTransient:
+		return "transient"
+	case ErrorClassProtocol:
+		return "protocol"
+	default:
+		return "unknown"
+	}
+}
+
+// RetryPolicy decides whether and how a failed exchange is retried.
+type RetryPolicy interface {
+	// Classify returns the class of an error returned by an exchange.
+	Classify(err error) ErrorClass
+
+	// Retry is called after the given attempt (starting at 1) failed with an
+	// error of the given class. It reports whether to retry, how long to wait
+	// before the next attempt and whether to reconnect before it.
+	Retry(attempt int, class ErrorClass) (retry bool, delay time.Duration, reconnect bool)
+}
+
+// BackoffRetryPolicy is a RetryPolicy with exponential backoff and jitter.
+type BackoffRetryPolicy struct {
+	MaxAttempts    int           // Maximum number of attempts including the first one.
+	InitialBackoff time.Duration // Delay before the first retry.
+	MaxBackoff     time.Duration // Upper bound for the delay (0 for no bound).
+	Multiplier     float64       // Factor by which the delay grows per retry (defaults to 2).
+	Jitter         float64       // Fraction of the delay that is randomised, from 0 to 1.
+	Reconnect      bool          // Reconnect before retrying after a transient error.
+	RetryProtocol  bool          // Retry protocol errors in addition to transient errors.
+}
+
+// DefaultRetryPolicy is used when a transporter has no RetryPolicy. It retries
+// a transient error once on a new connection.
+var DefaultRetryPolicy RetryPolicy = &BackoffRetryPolicy{
+	MaxAttempts: 2,
+	Reconnect:   true,
+}
+
+// Classify returns the class of an error returned by an exchange.
+//
+// Parameters:
+//   - err: The error to classify.
+//
+// Returns:
+//   - The class of the error as determined by ClassifyError.
+func (p *BackoffRetryPolicy) Classify(err error) ErrorClass {
+	return ClassifyError(err)
+}
+
+// Retry reports whether to retry after a failed attempt.
+//
+// Parameters:
+//   - attempt: The number of the failed attempt, starting at 1.
+//   - class: The class of the error.
+//
+// Returns:
+//   - retry: Whether the exchange should be attempted again.
+//   - delay: The delay before the next attempt.
+//   - reconnect: Whether to reconnect before the next attempt.
+func (p *BackoffRetryPolicy) Retry(attempt int, class ErrorClass) (retry bool, delay time.Duration, reconnect bool) {
+	if attempt >= p.MaxAttempts {
+		return false, 0, false
+	}
+	switch class {
+	case ErrorClassTransient:
+		return true, p.backoff(attempt), p.Reconnect
+	case ErrorClassProtocol:
+		return p.RetryProtocol, p.backoff(attempt), false
+	default:
+		return false, 0, false
+	}
+}
+
+// backoff calculates the delay after the given attempt.
+//
+// Parameters:
+//   - attempt: The number of the failed attempt, starting at 1.
+//
+// Returns:
+//   - The delay including jitter.
+func (p *BackoffRetryPolicy) backoff(attempt int) time.Duration {
+	multiplier := p.Multiplier
+	if multiplier == 0 {
+		multiplier = 2
+	}
+	delay := float64(p.InitialBackoff) * math.Pow(multiplier, float64(attempt-1))
+	if p.MaxBackoff > 0 && delay > float64(p.MaxBackoff) {
+		delay = float64(p.MaxBackoff)
+	}
+	if p.Jitter > 0 {
+		delay -= delay * min(p.Jitter, 1) * rand.Float64()
+	}
+	return time.Duration(delay)
+}
+
+// ClassifyError classifies errors returned by an exchange. Context errors are
+// fatal, connection errors and timeouts are transient and invalid frames are
+// protocol errors.
+//
+// Parameters:
+//   - err: The error to classify.
+//
+// Returns:
+//   - The class of the error.
+func ClassifyError(err error) ErrorClass {
+	switch {
+	case errors.Is(err, context.Canceled), errors.Is(err, context.DeadlineExceeded):
+		return ErrorClassFatal
+	case errors.Is(err, io.EOF),
+		errors.Is(err, io.ErrUnexpectedEOF),
+		errors.Is(err, net.ErrClosed),
+		errors.Is(err, os.ErrDeadlineExceeded),
+		errors.Is(err, syscall.EPIPE),
+		errors.Is(err, syscall.ECONNRESET),
+		errors.Is(err, syscall.ECONNABORTED),
+		errors.Is(err, syscall.ECONNREFUSED):
+		return ErrorClassTransient
+	}
+	var netErr net.Error
+	if errors.As(err, &netErr) && netErr.Timeout() {
+		return ErrorClassTransient
+	}
+	var frameErr *FrameError
+	if errors.As(err, &frameErr) {
+		return ErrorClassProtocol
+	}
+	return ErrorClassFatal
+}
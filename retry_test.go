@@ -0,0 +1,200 @@
+package gosolarman
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/grid-x/modbus"
+)
+
+func TestClassifyError(t *testing.T) {
+	tests := []struct {
+		err      error
+		expected ErrorClass
+	}{
+		{io.EOF, ErrorClassTransient},
+		{fmt.Errorf("failed to read: %w", syscall.ECONNRESET), ErrorClassTransient},
+		{&net.OpError{Op: "write", Err: syscall.EPIPE}, ErrorClassTransient},
+		{fmt.Errorf("failed to read: %w", context.DeadlineExceeded), ErrorClassFatal},
+		{fmt.Errorf("invalid response: %w", newFrameError(ErrChecksum, 0x42, 0x43, nil)), ErrorClassProtocol},
+		{errors.New("unknown"), ErrorClassFatal},
+	}
+	for _, test := range tests {
+		if class := ClassifyError(test.err); class != test.expected {
+			t.Errorf("Expected class %s for %v, got %s", test.expected, test.err, class)
+		}
+	}
+}
+
+func TestBackoffRetryPolicy(t *testing.T) {
+	policy := &BackoffRetryPolicy{
+		MaxAttempts:    4,
+		InitialBackoff: 100 * time.Millisecond,
+		MaxBackoff:     300 * time.Millisecond,
+		Reconnect:      true,
+	}
+
+	expected := []time.Duration{100 * time.Millisecond, 200 * time.Millisecond, 300 * time.Millisecond}
+	for i, want := range expected {
+		retry, delay, reconnect := policy.Retry(i+1, ErrorClassTransient)
+		if !retry || !reconnect {
+			t.Errorf("Expected retry with reconnect after attempt %d", i+1)
+		}
+		if delay != want {
+			t.Errorf("Expected delay %v after attempt %d, got %v", want, i+1, delay)
+		}
+	}
+	if retry, _, _ := policy.Retry(4, ErrorClassTransient); retry {
+		t.Errorf("Expected no retry after MaxAttempts")
+	}
+	if retry, _, _ := policy.Retry(1, ErrorClassProtocol); retry {
+		t.Errorf("Expected no retry for protocol errors")
+	}
+	if retry, _, _ := policy.Retry(1, ErrorClassFatal); retry {
+		t.Errorf("Expected no retry for fatal errors")
+	}
+}
+
+func TestBackoffJitter(t *testing.T) {
+	policy := &BackoffRetryPolicy{
+		MaxAttempts:    2,
+		InitialBackoff: 100 * time.Millisecond,
+		Jitter:         0.5,
+	}
+	for range 100 {
+		_, delay, _ := policy.Retry(1, ErrorClassTransient)
+		if delay < 50*time.Millisecond || delay > 100*time.Millisecond {
+			t.Fatalf("Expected delay between 50ms and 100ms, got %v", delay)
+		}
+	}
+}
+
+// eofConn is a connection that fails every read with io.EOF.
+type eofConn struct {
+	mockConn
+	closed bool
+}
+
+func (c *eofConn) Read(b []byte) (n int, err error) { return 0, io.EOF }
+func (c *eofConn) Close() error                     { c.closed = true; return nil }
+
+func TestSendClosesBrokenConnection(t *testing.T) {
+	broken := &eofConn{}
+	handler := &solarmanTransporter{
+		Address:     "127.0.0.1:1",
+		conn:        broken,
+		RetryPolicy: &BackoffRetryPolicy{MaxAttempts: 1},
+	}
+
+	_, err := handler.Send([]byte{StartByte})
+	if !errors.Is(err, io.EOF) {
+		t.Fatalf("Expected io.EOF, got %v", err)
+	}
+	if !broken.closed || handler.conn != nil {
+		t.Errorf("Expected broken connection to be closed")
+	}
+}
+
+func TestSendRetryReconnects(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Listen failed: %v", err)
+	}
+	defer listener.Close()
+	go func() {
+		conn, err := listener.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		respond(t, conn, 0x0001)
+	}()
+
+	handler := NewSolarmanClientHandler(listener.Addr().String(), 0x12345678)
+	handler.SlaveID = 0x01
+	handler.conn = &eofConn{}
+	client := NewContextClient(handler)
+
+	results, err := client.ReadHoldingRegistersContext(context.Background(), 0, 1)
+	if err != nil {
+		t.Fatalf("ReadHoldingRegistersContext failed: %v", err)
+	}
+	if len(results) != 2 || results[1] != 0x01 {
+		t.Errorf("Expected results 0001, got %X", results)
+	}
+}
+
+func TestSendRetriesProtocolErrors(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Listen failed: %v", err)
+	}
+	defer listener.Close()
+	go func() {
+		conn, err := listener.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		reader := newFrameReader(conn)
+		request, err := reader.ReadFrame()
+		if err != nil {
+			return
+		}
+		response := responseFrame(request, 0x0001)
+		response[len(response)-2]++
+		conn.Write(response)
+		respond(t, conn, 0x0002)
+	}()
+
+	handler := NewSolarmanClientHandler(listener.Addr().String(), 0x12345678)
+	handler.SlaveID = 0x01
+	handler.RetryPolicy = &BackoffRetryPolicy{MaxAttempts: 2, RetryProtocol: true}
+	client := modbus.NewClient(handler)
+
+	results, err := client.ReadHoldingRegisters(0, 1)
+	if err != nil {
+		t.Fatalf("ReadHoldingRegisters failed: %v", err)
+	}
+	if len(results) != 2 || results[1] != 0x02 {
+		t.Errorf("Expected results 0002 from the second attempt, got %X", results)
+	}
+}
+
+func TestSendReturnsProtocolErrors(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Listen failed: %v", err)
+	}
+	defer listener.Close()
+	go func() {
+		conn, err := listener.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		request, err := newFrameReader(conn).ReadFrame()
+		if err != nil {
+			return
+		}
+		response := responseFrame(request, 0x0001)
+		response[len(response)-4]++
+		response[len(response)-2] = CheckSum(response[1 : len(response)-2])
+		conn.Write(response)
+	}()
+
+	handler := NewSolarmanClientHandler(listener.Addr().String(), 0x12345678)
+	handler.SlaveID = 0x01
+	client := NewContextClient(handler)
+
+	_, err = client.ReadHoldingRegistersContext(context.Background(), 0, 1)
+	if !errors.Is(err, ErrCRC) {
+		t.Errorf("Expected ErrCRC without RetryProtocol, got %v", err)
+	}
+}
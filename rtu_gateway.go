@@ -0,0 +1,160 @@
+package gosolarman
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"net"
+	"os"
+
+	"github.com/grid-x/modbus"
+)
+
+// errUnsupportedFunction is returned when the length of an RTU request cannot
+// be determined from its function code.
+var errUnsupportedFunction = errors.New("unsupported function code")
+
+// RTUOverTCPGateway is a server for raw Modbus RTU frames over TCP that
+// forwards the requests of its clients to the slaves behind a data logging
+// stick, so that RTU-capable software can read the inverter. Requests with a
+// wrong CRC are dropped without a response, like on a serial line.
+type RTUOverTCPGateway struct {
+	gateway
+}
+
+// NewRTUOverTCPGateway creates a new RTU-over-TCP gateway on top of a Solarman client handler.
+//
+// Parameters:
+//   - handler: The handler that forwards the requests to the data logging stick.
+//
+// Returns:
+//   - A pointer to the created RTUOverTCPGateway.
+func NewRTUOverTCPGateway(handler *SolarmanClientHandler) *RTUOverTCPGateway {
+	return &RTUOverTCPGateway{
+		gateway: gateway{
+			handler: handler,
+			server:  newServer(),
+		},
+	}
+}
+
+// ListenAndServe listens on a TCP address and serves clients until the gateway is closed.
+//
+// Parameters:
+//   - address: The address to listen on (e.g., ":502").
+//
+// Returns:
+//   - An error if listening fails, or net.ErrClosed after Close.
+func (g *RTUOverTCPGateway) ListenAndServe(address string) error {
+	listener, err := net.Listen("tcp", address)
+	if err != nil {
+		return err
+	}
+	return g.Serve(listener)
+}
+
+// Serve accepts clients on listener until the gateway is closed.
+//
+// Parameters:
+//   - listener: The listener to accept clients on. It is closed by Serve.
+//
+// Returns:
+//   - An error if accepting fails, or net.ErrClosed after Close.
+func (g *RTUOverTCPGateway) Serve(listener net.Listener) error {
+	return g.server.serve(listener, g.serveConn)
+}
+
+// serveConn answers the RTU requests of one client until it disconnects.
+//
+// Parameters:
+//   - ctx: The context of the gateway, cancelled on Close.
+//   - conn: The connection of the client.
+func (g *RTUOverTCPGateway) serveConn(ctx context.Context, conn net.Conn) {
+	client := conn.RemoteAddr().String()
+	reader := bufio.NewReaderSize(conn, maxPDULength+3)
+	for {
+		frame, err := readRTURequest(reader)
+		if errors.Is(err, errUnsupportedFunction) {
+			g.handler.log(ctx, slog.LevelWarn, "unsupported RTU request", slog.String("client", client), slog.Int("function_code", int(frame[1])))
+			response := marshalRTUFrame(frame[0], exceptionPDU(frame[1], modbus.ExceptionCodeIllegalFunction))
+			if _, err := conn.Write(response); err != nil {
+				return
+			}
+			continue
+		}
+		if err != nil {
+			if !errors.Is(err, io.EOF) && !errors.Is(err, net.ErrClosed) && !errors.Is(err, os.ErrDeadlineExceeded) {
+				g.handler.log(ctx, slog.LevelWarn, "invalid RTU frame", slog.String("client", client), slog.Any("error", err))
+			}
+			return
+		}
+
+		slaveID, pdu, err := parseRTUFrame(frame)
+		if err != nil {
+			g.handler.log(ctx, slog.LevelWarn, "dropped RTU request", slog.String("client", client), slog.Any("error", err))
+			continue
+		}
+		response := g.forward(ctx, client, slaveID, &pdu)
+		if ctx.Err() != nil {
+			return
+		}
+		if _, err := conn.Write(marshalRTUFrame(slaveID, response)); err != nil {
+			return
+		}
+	}
+}
+
+// readRTURequest reads one Modbus RTU request frame, using its function code
+// to determine its length.
+//
+// Parameters:
+//   - r: The reader of the client connection.
+//
+// Returns:
+//   - frame: The request frame including the CRC. On errUnsupportedFunction,
+//     the slave ID and function code, after dropping all buffered input.
+//   - err: An error if reading fails or the function code is unsupported.
+func readRTURequest(r *bufio.Reader) (frame []byte, err error) {
+	head, err := r.Peek(2)
+	if err != nil {
+		if len(head) > 0 {
+			return nil, unexpectedEOF(err)
+		}
+		return nil, err
+	}
+
+	var length int
+	switch head[1] {
+	case modbus.FuncCodeReadCoils, modbus.FuncCodeReadDiscreteInputs,
+		modbus.FuncCodeReadHoldingRegisters, modbus.FuncCodeReadInputRegisters,
+		modbus.FuncCodeWriteSingleCoil, modbus.FuncCodeWriteSingleRegister:
+		length = 8
+	case modbus.FuncCodeWriteMultipleCoils, modbus.FuncCodeWriteMultipleRegisters:
+		if head, err = r.Peek(7); err != nil {
+			return nil, unexpectedEOF(err)
+		}
+		length = 9 + int(head[6])
+	case modbus.FuncCodeMaskWriteRegister:
+		length = 10
+	case modbus.FuncCodeReadWriteMultipleRegisters:
+		if head, err = r.Peek(11); err != nil {
+			return nil, unexpectedEOF(err)
+		}
+		length = 13 + int(head[10])
+	case modbus.FuncCodeReadFIFOQueue:
+		length = 6
+	default:
+		// The end of the frame is unknown, so drop everything received so far.
+		frame = []byte{head[0], head[1]}
+		r.Discard(r.Buffered())
+		return frame, errUnsupportedFunction
+	}
+
+	frame = make([]byte, length)
+	if _, err = io.ReadFull(r, frame); err != nil {
+		return nil, unexpectedEOF(err)
+	}
+	return frame, nil
+}
@@ -0,0 +1,112 @@
+package gosolarman
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"io"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/grid-x/modbus"
+)
+
+func TestRTUOverTCPGateway(t *testing.T) {
+	logger := fakeLogger(t, func(request []byte) []byte {
+		if request[27] == modbus.FuncCodeWriteSingleRegister {
+			return rtuResponseFrame(request, request[27], request[28:32])
+		}
+		return responseFrame(request, uint16(request[26]), uint16(request[28])<<8|uint16(request[29]))
+	})
+	defer logger.Close()
+
+	gateway := NewRTUOverTCPGateway(NewSolarmanClientHandler(logger.Addr().String(), 0x12345678))
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Listen failed: %v", err)
+	}
+	go gateway.Serve(listener)
+	defer gateway.Close()
+
+	handler := modbus.NewRTUOverTCPClientHandler(listener.Addr().String())
+	handler.SlaveID = 0x03
+	handler.Timeout = 2 * time.Second
+	defer handler.Close()
+	client := modbus.NewClient(handler)
+
+	results, err := client.ReadHoldingRegisters(0x0100, 2)
+	if err != nil {
+		t.Fatalf("ReadHoldingRegisters failed: %v", err)
+	}
+	if expected := []byte{0x00, 0x03, 0x01, 0x00}; !bytes.Equal(results, expected) {
+		t.Errorf("Expected %X, got %X", expected, results)
+	}
+	results, err = client.WriteSingleRegister(0x0010, 0x1234)
+	if err != nil {
+		t.Fatalf("WriteSingleRegister failed: %v", err)
+	}
+	if expected := []byte{0x12, 0x34}; !bytes.Equal(results, expected) {
+		t.Errorf("Expected %X, got %X", expected, results)
+	}
+
+	conn, err := net.Dial("tcp", listener.Addr().String())
+	if err != nil {
+		t.Fatalf("Dial failed: %v", err)
+	}
+	defer conn.Close()
+	conn.SetDeadline(time.Now().Add(2 * time.Second))
+
+	// A request with a wrong CRC is dropped, the next one is answered.
+	request := marshalRTUFrame(0x01, &modbus.ProtocolDataUnit{FunctionCode: 0x03, Data: dataBlock(0x0020, 1)})
+	corrupt := append([]byte(nil), request...)
+	corrupt[len(corrupt)-1]++
+	conn.Write(append(corrupt, request...))
+	response := make([]byte, 9)
+	if _, err := io.ReadFull(conn, response); err != nil {
+		t.Fatalf("ReadFull failed: %v", err)
+	}
+	expected := marshalRTUFrame(0x01, &modbus.ProtocolDataUnit{FunctionCode: 0x03, Data: append([]byte{0x04}, dataBlock(0x0001, 0x0020)...)})
+	if !bytes.Equal(response, expected) {
+		t.Errorf("Expected %X, got %X", expected, response)
+	}
+
+	// Requests of unknown length are answered with an illegal function exception.
+	conn.Write([]byte{0x01, 0x2B, 0x0E, 0x01, 0x00, 0x70, 0x77})
+	response = make([]byte, 5)
+	if _, err := io.ReadFull(conn, response); err != nil {
+		t.Fatalf("ReadFull failed: %v", err)
+	}
+	expected = marshalRTUFrame(0x01, exceptionPDU(0x2B, modbus.ExceptionCodeIllegalFunction))
+	if !bytes.Equal(response, expected) {
+		t.Errorf("Expected %X, got %X", expected, response)
+	}
+}
+
+func TestReadRTURequest(t *testing.T) {
+	tests := []struct {
+		name  string
+		frame []byte
+	}{
+		{"read", marshalRTUFrame(0x01, &modbus.ProtocolDataUnit{FunctionCode: 0x03, Data: dataBlock(0x0010, 2)})},
+		{"write multiple", marshalRTUFrame(0x01, &modbus.ProtocolDataUnit{FunctionCode: 0x10, Data: dataBlockSuffix(dataBlock(1, 2), 0x0010, 2)})},
+		{"read write multiple", marshalRTUFrame(0x01, &modbus.ProtocolDataUnit{FunctionCode: 0x17, Data: dataBlockSuffix(dataBlock(1), 0x0010, 2, 0x0020, 1)})},
+	}
+	for _, test := range tests {
+		reader := bufio.NewReader(bytes.NewReader(append(test.frame, test.frame...)))
+		for range 2 {
+			frame, err := readRTURequest(reader)
+			if err != nil {
+				t.Fatalf("%s: readRTURequest failed: %v", test.name, err)
+			}
+			if !bytes.Equal(frame, test.frame) {
+				t.Errorf("%s: expected %X, got %X", test.name, test.frame, frame)
+			}
+		}
+	}
+
+	reader := bufio.NewReader(bytes.NewReader([]byte{0x01, 0x03, 0x00}))
+	if _, err := readRTURequest(reader); !errors.Is(err, io.ErrUnexpectedEOF) {
+		t.Errorf("Expected io.ErrUnexpectedEOF, got %v", err)
+	}
+}
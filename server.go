@@ -0,0 +1,130 @@
+package gosolarman
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net"
+	"sync"
+)
+
+// server accepts clients on listeners and tracks them so that they can be
+// closed together. It is shared by the proxy and the gateways.
+type server struct {
+	mu      sync.Mutex
+	closers map[io.Closer]struct{} // Listeners and client connections to close on close.
+	closed  bool
+	ctx     context.Context // Context of the exchanges, cancelled on close.
+	cancel  context.CancelFunc
+	wg      sync.WaitGroup
+}
+
+// newServer creates a new server.
+//
+// Returns:
+//   - A pointer to the created server.
+func newServer() *server {
+	ctx, cancel := context.WithCancel(context.Background())
+	return &server{
+		closers: make(map[io.Closer]struct{}),
+		ctx:     ctx,
+		cancel:  cancel,
+	}
+}
+
+// serve accepts clients on listener and handles each in its own goroutine
+// until the server is closed.
+//
+// Parameters:
+//   - listener: The listener to accept clients on. It is closed by serve.
+//   - handle: Called with the server context and every accepted connection.
+//
+// Returns:
+//   - An error if accepting fails, or net.ErrClosed after close.
+func (s *server) serve(listener net.Listener, handle func(ctx context.Context, conn net.Conn)) error {
+	if !s.track(listener) {
+		return net.ErrClosed
+	}
+	defer s.wg.Done()
+	defer s.untrack(listener)
+
+	for {
+		conn, err := listener.Accept()
+		if err != nil {
+			if s.isClosed() {
+				return net.ErrClosed
+			}
+			return err
+		}
+		if !s.track(conn) {
+			return net.ErrClosed
+		}
+		go func() {
+			defer s.wg.Done()
+			defer s.untrack(conn)
+			handle(s.ctx, conn)
+		}()
+	}
+}
+
+// close stops accepting clients, disconnects all clients, cancels the
+// server context and waits for the handlers to return.
+//
+// Returns:
+//   - An error if closing a listener fails.
+func (s *server) close() (err error) {
+	s.mu.Lock()
+	s.closed = true
+	s.cancel()
+	for closer := range s.closers {
+		if _, ok := closer.(net.Listener); ok {
+			if closeErr := closer.Close(); closeErr != nil && !errors.Is(closeErr, net.ErrClosed) {
+				err = closeErr
+			}
+		} else {
+			closer.Close()
+		}
+	}
+	s.mu.Unlock()
+
+	s.wg.Wait()
+	return err
+}
+
+// track registers a listener or client connection so that close can close
+// it, and adds it to the wait group.
+//
+// Parameters:
+//   - closer: The listener or connection.
+//
+// Returns:
+//   - false if the server is already closed, in which case closer is closed.
+func (s *server) track(closer io.Closer) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.closed {
+		closer.Close()
+		return false
+	}
+	s.closers[closer] = struct{}{}
+	s.wg.Add(1)
+	return true
+}
+
+// untrack closes a listener or client connection registered by track.
+//
+// Parameters:
+//   - closer: The listener or connection.
+func (s *server) untrack(closer io.Closer) {
+	s.mu.Lock()
+	delete(s.closers, closer)
+	s.mu.Unlock()
+	closer.Close()
+}
+
+// isClosed reports whether close has been called.
+func (s *server) isClosed() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.closed
+}
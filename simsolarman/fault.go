@@ -0,0 +1,178 @@
+package simsolarman
+
+import (
+	"context"
+	"encoding/binary"
+	"log/slog"
+	"net"
+	"time"
+
+	"github.com/tlmnb/gosolarman"
+)
+
+// FaultKind is the kind of misbehaviour of a Fault.
+type FaultKind int
+
+const (
+	// FaultNone sends the response unchanged.
+	FaultNone FaultKind = iota
+
+	// FaultDrop sends no response.
+	FaultDrop
+
+	// FaultDelay sends the response after Fault.Delay.
+	FaultDelay
+
+	// FaultDuplicate sends the response twice.
+	FaultDuplicate
+
+	// FaultChecksum sends the response with a wrong checksum.
+	FaultChecksum
+
+	// FaultCRC sends the response with a wrong CRC of the Modbus RTU frame
+	// and a correct checksum.
+	FaultCRC
+
+	// FaultSequence sends the response with the previous sequence number,
+	// like a stale response to an earlier request.
+	FaultSequence
+
+	// FaultUnsolicited sends a heartbeat frame before the response.
+	FaultUnsolicited
+
+	// FaultClose sends the first half of the response and closes the connection.
+	FaultClose
+)
+
+// faultNames are the names of the fault kinds.
+var faultNames = map[FaultKind]string{
+	FaultNone:        "none",
+	FaultDrop:        "drop",
+	FaultDelay:       "delay",
+	FaultDuplicate:   "duplicate",
+	FaultChecksum:    "checksum",
+	FaultCRC:         "crc",
+	FaultSequence:    "sequence",
+	FaultUnsolicited: "unsolicited",
+	FaultClose:       "close",
+}
+
+// String returns the name of the fault kind.
+//
+// Returns:
+//   - The name (e.g., "drop").
+func (k FaultKind) String() string {
+	if name, ok := faultNames[k]; ok {
+		return name
+	}
+	return "unknown"
+}
+
+// Fault is a misbehaviour of the simulated data logging stick when answering one request.
+type Fault struct {
+	Kind  FaultKind     // Kind of the misbehaviour.
+	Delay time.Duration // Delay of the response for FaultDelay.
+}
+
+// InjectFaults appends faults to the script of the simulator. Each answered
+// request consumes the next fault of the script, in order. Requests that the
+// simulator ignores, e.g. because of a wrong logger serial number, do not
+// consume faults. Once the script is exhausted, responses are sent unchanged.
+//
+// Parameters:
+//   - faults: The faults for the next answered requests.
+func (s *Simulator) InjectFaults(faults ...Fault) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.faults = append(s.faults, faults...)
+}
+
+// nextFault removes the next fault from the script.
+//
+// Returns:
+//   - The next fault, or a FaultNone fault if the script is exhausted.
+func (s *Simulator) nextFault() Fault {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if len(s.faults) == 0 {
+		return Fault{Kind: FaultNone}
+	}
+	fault := s.faults[0]
+	s.faults = s.faults[1:]
+	return fault
+}
+
+// respond sends a response to a client, applying the next fault of the script.
+//
+// Parameters:
+//   - ctx: The context of the simulator, cancelled on Close.
+//   - conn: The connection of the client.
+//   - response: The response frame.
+//
+// Returns:
+//   - false if the connection must be closed.
+func (s *Simulator) respond(ctx context.Context, conn net.Conn, response []byte) bool {
+	fault := s.nextFault()
+	if fault.Kind != FaultNone {
+		s.log(slog.LevelDebug, "injected fault", slog.String("client", conn.RemoteAddr().String()), slog.String("fault", fault.Kind.String()))
+		if s.onFault != nil {
+			s.onFault(fault)
+		}
+	}
+
+	frames := [][]byte{response}
+	switch fault.Kind {
+	case FaultDrop:
+		return true
+	case FaultDelay:
+		timer := time.NewTimer(fault.Delay)
+		defer timer.Stop()
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+			return false
+		}
+	case FaultDuplicate:
+		frames = append(frames, response)
+	case FaultChecksum:
+		response[len(response)-2]++
+	case FaultCRC:
+		response[len(response)-trailerLength-1]++
+		updateChecksum(response)
+	case FaultSequence:
+		sequence := binary.LittleEndian.Uint16(response[5:7])
+		binary.LittleEndian.PutUint16(response[5:7], sequence-1)
+		updateChecksum(response)
+	case FaultUnsolicited:
+		heartbeat, err := (&gosolarman.Frame{
+			Header: &gosolarman.Header{
+				ControlCode:        gosolarman.ControlCodeHeartbeat,
+				SequenceNumber:     binary.LittleEndian.Uint16(response[5:7]),
+				LoggerSerialNumber: s.LoggerSerial,
+			},
+			Payload: &gosolarman.HeartbeatPayload{Data: []byte{0x00}},
+		}).Marshal()
+		if err != nil {
+			return false
+		}
+		frames = [][]byte{heartbeat, response}
+	case FaultClose:
+		conn.Write(response[:len(response)/2])
+		return false
+	}
+
+	for _, frame := range frames {
+		if _, err := conn.Write(frame); err != nil {
+			return false
+		}
+	}
+	return true
+}
+
+// updateChecksum recalculates the checksum of a frame after modifying it.
+//
+// Parameters:
+//   - frame: The frame, from StartByte to EndByte inclusive.
+func updateChecksum(frame []byte) {
+	frame[len(frame)-2] = gosolarman.CheckSum(frame[1 : len(frame)-2])
+}
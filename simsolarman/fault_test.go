@@ -0,0 +1,118 @@
+package simsolarman
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/tlmnb/gosolarman"
+)
+
+func TestSimulatorFaults(t *testing.T) {
+	tests := []struct {
+		fault    Fault
+		expected error // Expected error of the faulty exchange, or nil for success.
+	}{
+		// Exchanges that never complete are cancelled once the fault is applied.
+		{Fault{Kind: FaultDrop}, context.Canceled},
+		{Fault{Kind: FaultDelay, Delay: time.Hour}, context.Canceled},
+		{Fault{Kind: FaultDelay, Delay: 10 * time.Millisecond}, nil},
+		{Fault{Kind: FaultDuplicate}, nil},
+		{Fault{Kind: FaultChecksum}, gosolarman.ErrChecksum},
+		{Fault{Kind: FaultCRC}, gosolarman.ErrCRC},
+		{Fault{Kind: FaultSequence}, context.Canceled},
+		{Fault{Kind: FaultUnsolicited}, nil},
+		{Fault{Kind: FaultClose}, nil}, // The client reconnects and retries.
+	}
+	for _, test := range tests {
+		t.Run(test.fault.Kind.String(), func(t *testing.T) {
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+			simulator := NewSimulator(0x12345678)
+			simulator.Slave(0x01).SetHoldingRegisters(0, 0x0102)
+			simulator.InjectFaults(test.fault)
+			if errors.Is(test.expected, context.Canceled) {
+				simulator.onFault = func(Fault) { cancel() }
+			}
+			_, client := connect(t, simulator)
+
+			results, err := client.ReadHoldingRegistersContext(ctx, 0, 1)
+			switch {
+			case test.expected == nil && err != nil:
+				t.Fatalf("Expected success, got %v", err)
+			case test.expected != nil && !errors.Is(err, test.expected):
+				t.Fatalf("Expected %v, got %v", test.expected, err)
+			case err == nil && !bytes.Equal(results, []byte{0x01, 0x02}):
+				t.Fatalf("Expected 0102, got %X", results)
+			}
+
+			// The client recovers once the stick behaves again.
+			if results, err = client.ReadHoldingRegistersContext(context.Background(), 0, 1); err != nil {
+				t.Fatalf("Expected recovery, got %v", err)
+			}
+			if !bytes.Equal(results, []byte{0x01, 0x02}) {
+				t.Errorf("Expected 0102, got %X", results)
+			}
+		})
+	}
+}
+
+func TestSimulatorFaultScript(t *testing.T) {
+	simulator := NewSimulator(0x12345678)
+	simulator.Slave(0x01).SetHoldingRegisters(0, 0x0102)
+	simulator.InjectFaults(Fault{Kind: FaultUnsolicited}, Fault{Kind: FaultUnsolicited})
+	address, err := simulator.Start()
+	if err != nil {
+		t.Fatalf("Start failed: %v", err)
+	}
+	defer simulator.Close()
+
+	var heartbeats atomic.Int32
+	handler := gosolarman.NewSolarmanClientHandler(address, 0x12345678)
+	handler.SlaveID = 0x01
+	handler.Timeout = time.Second
+	handler.OnUnsolicitedFrame = func(frame []byte) {
+		if binary.LittleEndian.Uint16(frame[3:5]) == gosolarman.ControlCodeHeartbeat {
+			heartbeats.Add(1)
+		}
+	}
+	defer handler.Close()
+	client := gosolarman.NewContextClient(handler)
+
+	for range 3 {
+		if _, err := client.ReadHoldingRegistersContext(context.Background(), 0, 1); err != nil {
+			t.Fatalf("ReadHoldingRegistersContext failed: %v", err)
+		}
+	}
+	if got := heartbeats.Load(); got != 2 {
+		t.Errorf("Expected 2 heartbeats, got %d", got)
+	}
+}
+
+// connect starts a configured simulator and connects a client to it.
+//
+// Parameters:
+//   - t: The test.
+//   - simulator: The simulator to start.
+//
+// Returns:
+//   - The handler of the client.
+//   - The client connected to the simulator.
+func connect(t *testing.T, simulator *Simulator) (*gosolarman.SolarmanClientHandler, *gosolarman.ContextClient) {
+	t.Helper()
+	address, err := simulator.Start()
+	if err != nil {
+		t.Fatalf("Start failed: %v", err)
+	}
+	t.Cleanup(func() { simulator.Close() })
+
+	handler := gosolarman.NewSolarmanClientHandler(address, 0x12345678)
+	handler.SlaveID = 0x01
+	handler.Timeout = time.Second
+	t.Cleanup(func() { handler.Close() })
+	return handler, gosolarman.NewContextClient(handler)
+}
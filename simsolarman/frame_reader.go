@@ -0,0 +1,97 @@
+package simsolarman
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+
+	"github.com/tlmnb/gosolarman"
+)
+
+// frameReader reads complete Solarman frames from a byte stream.
+//
+// It synchronises on StartByte, uses the Length field of the header to read
+// exactly one frame and keeps surplus bytes buffered for the next call. Bytes
+// that do not form a valid frame are skipped. It mirrors the unexported frame
+// reader of the gosolarman package, so that the simulator accepts the same
+// frames as the proxy.
+type frameReader struct {
+	r *bufio.Reader
+}
+
+// newFrameReader creates a new frame reader on top of r.
+//
+// Parameters:
+//   - r: The underlying byte stream (e.g., a TCP connection).
+//
+// Returns:
+//   - A pointer to the created frameReader.
+func newFrameReader(r io.Reader) *frameReader {
+	return &frameReader{
+		r: bufio.NewReaderSize(r, headerLength+maxPayloadLength+trailerLength),
+	}
+}
+
+// ReadFrame reads the next complete frame from the stream.
+//
+// Returns:
+//   - frame: The raw frame, from StartByte to EndByte inclusive.
+//   - err: An error if the underlying stream fails.
+func (fr *frameReader) ReadFrame() (frame []byte, err error) {
+	for {
+		if err = fr.sync(); err != nil {
+			return nil, err
+		}
+
+		header, err := fr.r.Peek(headerLength)
+		if err != nil {
+			return nil, unexpectedEOF(err)
+		}
+		length := int(header[1]) | int(header[2])<<8
+		if length > maxPayloadLength || header[3] != controlCodeSuffix {
+			// Not a plausible header, resync after this start byte.
+			fr.r.Discard(1)
+			continue
+		}
+
+		size := headerLength + length + trailerLength
+		data, err := fr.r.Peek(size)
+		if err != nil {
+			return nil, unexpectedEOF(err)
+		}
+		if data[size-1] != gosolarman.EndByte {
+			fr.r.Discard(1)
+			continue
+		}
+
+		frame = make([]byte, size)
+		copy(frame, data)
+		fr.r.Discard(size)
+		return frame, nil
+	}
+}
+
+// sync discards bytes until the next byte in the stream is StartByte.
+//
+// Returns:
+//   - An error if the underlying stream fails.
+func (fr *frameReader) sync() error {
+	for {
+		b, err := fr.r.Peek(1)
+		if err != nil {
+			return err
+		}
+		if b[0] == gosolarman.StartByte {
+			return nil
+		}
+		fr.r.Discard(1)
+	}
+}
+
+// unexpectedEOF converts io.EOF in the middle of a frame into io.ErrUnexpectedEOF.
+func unexpectedEOF(err error) error {
+	if err == io.EOF {
+		return fmt.Errorf("incomplete frame: %w", io.ErrUnexpectedEOF)
+	}
+	return err
+}
@@ -0,0 +1,50 @@
+package simsolarman
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"testing"
+)
+
+var testFrame = []byte{
+	0xA5,       // Start
+	0x17, 0x00, // Length (23 bytes)
+	0x10, 0x45, // Control Code
+	0x01, 0x02, // Sequence Number
+	0x78, 0x56, 0x34, 0x12, // Logger Serial Number
+	0x02,       // Frame Type
+	0x00, 0x00, // Sensor Type
+	0x00, 0x00, 0x00, 0x00, // Total Working Time
+	0x00, 0x00, 0x00, 0x00, // Power On Time
+	0x00, 0x00, 0x00, 0x00, // Offset Time
+	0x01, 0x03, 0x00, 0x00, 0x00, 0x01, 0x84, 0x0A, // Modbus RTU Frame
+	0x00, // Checksum (not checked by the reader)
+	0x15, // End
+}
+
+func TestReadFrameResync(t *testing.T) {
+	// A frame with a wrong end byte is skipped like any other garbage.
+	corrupt := bytes.Clone(testFrame)
+	corrupt[len(corrupt)-1] = 0x00
+	data := append([]byte{0x00, 0xFF, 0xA5, 0xFF, 0xFF}, corrupt...)
+	data = append(data, testFrame...)
+	reader := newFrameReader(bytes.NewReader(data))
+
+	frame, err := reader.ReadFrame()
+	if err != nil {
+		t.Fatalf("ReadFrame failed: %v", err)
+	}
+	if !bytes.Equal(frame, testFrame) {
+		t.Errorf("Expected frame %X, got %X", testFrame, frame)
+	}
+}
+
+func TestReadFrameTruncated(t *testing.T) {
+	reader := newFrameReader(bytes.NewReader(testFrame[:20]))
+
+	_, err := reader.ReadFrame()
+	if !errors.Is(err, io.ErrUnexpectedEOF) {
+		t.Errorf("Expected io.ErrUnexpectedEOF, got %v", err)
+	}
+}
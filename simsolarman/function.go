@@ -0,0 +1,85 @@
+package simsolarman
+
+import (
+	"encoding/binary"
+
+	"github.com/grid-x/modbus"
+)
+
+// execute answers a Modbus request PDU from the register banks of a slave.
+//
+// Parameters:
+//   - slave: The slave that receives the request.
+//   - request: The request PDU.
+//
+// Returns:
+//   - The response PDU, or an exception response if the request fails.
+func execute(slave *Slave, request *modbus.ProtocolDataUnit) *modbus.ProtocolDataUnit {
+	data := request.Data
+	switch request.FunctionCode {
+	case modbus.FuncCodeReadHoldingRegisters, modbus.FuncCodeReadInputRegisters:
+		if len(data) != 4 {
+			return exception(request.FunctionCode, modbus.ExceptionCodeIllegalDataValue)
+		}
+		address := binary.BigEndian.Uint16(data[0:2])
+		quantity := binary.BigEndian.Uint16(data[2:4])
+		if quantity < 1 || quantity > maxReadQuantity {
+			return exception(request.FunctionCode, modbus.ExceptionCodeIllegalDataValue)
+		}
+		read := slave.HoldingRegisters
+		if request.FunctionCode == modbus.FuncCodeReadInputRegisters {
+			read = slave.InputRegisters
+		}
+		values, ok := read(address, quantity)
+		if !ok {
+			return exception(request.FunctionCode, modbus.ExceptionCodeIllegalDataAddress)
+		}
+		response := make([]byte, 1, 1+2*len(values))
+		response[0] = byte(2 * len(values))
+		for _, value := range values {
+			response = binary.BigEndian.AppendUint16(response, value)
+		}
+		return &modbus.ProtocolDataUnit{FunctionCode: request.FunctionCode, Data: response}
+
+	case modbus.FuncCodeWriteSingleRegister:
+		if len(data) != 4 {
+			return exception(request.FunctionCode, modbus.ExceptionCodeIllegalDataValue)
+		}
+		address := binary.BigEndian.Uint16(data[0:2])
+		if !slave.writeHoldingRegisters(address, []uint16{binary.BigEndian.Uint16(data[2:4])}) {
+			return exception(request.FunctionCode, modbus.ExceptionCodeIllegalDataAddress)
+		}
+		return &modbus.ProtocolDataUnit{FunctionCode: request.FunctionCode, Data: append([]byte(nil), data...)}
+
+	case modbus.FuncCodeWriteMultipleRegisters:
+		if len(data) < 5 {
+			return exception(request.FunctionCode, modbus.ExceptionCodeIllegalDataValue)
+		}
+		address := binary.BigEndian.Uint16(data[0:2])
+		quantity := binary.BigEndian.Uint16(data[2:4])
+		if quantity < 1 || quantity > maxWriteQuantity || int(data[4]) != 2*int(quantity) || len(data) != 5+2*int(quantity) {
+			return exception(request.FunctionCode, modbus.ExceptionCodeIllegalDataValue)
+		}
+		values := make([]uint16, quantity)
+		for i := range values {
+			values[i] = binary.BigEndian.Uint16(data[5+2*i:])
+		}
+		if !slave.writeHoldingRegisters(address, values) {
+			return exception(request.FunctionCode, modbus.ExceptionCodeIllegalDataAddress)
+		}
+		return &modbus.ProtocolDataUnit{FunctionCode: request.FunctionCode, Data: append([]byte(nil), data[0:4]...)}
+	}
+	return exception(request.FunctionCode, modbus.ExceptionCodeIllegalFunction)
+}
+
+// exception creates an exception response PDU.
+//
+// Parameters:
+//   - functionCode: The function code of the request.
+//   - code: The Modbus exception code.
+//
+// Returns:
+//   - The exception response PDU.
+func exception(functionCode, code byte) *modbus.ProtocolDataUnit {
+	return &modbus.ProtocolDataUnit{FunctionCode: functionCode | 0x80, Data: []byte{code}}
+}
@@ -0,0 +1,265 @@
+// Package simsolarman provides a simulated Solarman data logging stick for
+// testing clients, gateways and pollers without hardware.
+package simsolarman
+
+import (
+	"context"
+	"log/slog"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/grid-x/modbus"
+	"github.com/tlmnb/gosolarman"
+)
+
+const (
+	// headerLength is the length of a Solarman frame header including the start byte.
+	headerLength = 11
+
+	// trailerLength is the length of a Solarman frame trailer (checksum and end byte).
+	trailerLength = 2
+
+	// maxPayloadLength is the largest payload accepted from clients.
+	maxPayloadLength = 1024
+
+	// controlCodeSuffix is the low byte shared by all Solarman control codes.
+	controlCodeSuffix = 0x10
+
+	// maxReadQuantity is the maximum number of registers of a read request.
+	maxReadQuantity = 125
+
+	// maxWriteQuantity is the maximum number of registers of a write multiple registers request.
+	maxWriteQuantity = 123
+
+	// statusOK is the status byte of responses.
+	statusOK = 0x01
+)
+
+// Simulator is a simulated data logging stick. It answers Solarman V5
+// requests with responses from the register banks of its slaves, and like a
+// real stick it ignores requests for another logger serial number and
+// answers requests for missing slaves without a Modbus RTU frame. Faults can
+// be injected with InjectFaults to test how clients handle misbehaving sticks.
+// It is safe for concurrent use, but LoggerSerial must not be modified while
+// serving.
+type Simulator struct {
+	LoggerSerial uint32 // Serial number of the simulated data logging stick.
+
+	// StructuredLogger receives records of connections and rejected frames.
+	StructuredLogger *slog.Logger
+
+	started time.Time         // Power on time of the simulated data logging stick.
+	server  *server           // Accepts and tracks the clients.
+	onFault func(fault Fault) // Called with every applied fault before the response is sent, for tests.
+
+	// replaceSequence replaces the second sequence number byte of responses
+	// with a frame counter, like many data logging sticks do, to test clients
+	// with LooseSequence. By default both bytes are echoed.
+	replaceSequence bool
+
+	mu      sync.Mutex
+	slaves  map[byte]*Slave
+	faults  []Fault // Script of faults for the next answered requests.
+	counter byte    // Frame counter for replaceSequence.
+}
+
+// NewSimulator creates a new simulator without slaves.
+//
+// Parameters:
+//   - loggerSerial: The serial number of the simulated data logging stick.
+//
+// Returns:
+//   - A pointer to the created Simulator.
+func NewSimulator(loggerSerial uint32) *Simulator {
+	return &Simulator{
+		LoggerSerial: loggerSerial,
+		started:      time.Now(),
+		server:       newServer(),
+		slaves:       make(map[byte]*Slave),
+	}
+}
+
+// Slave returns the slave with the given ID, adding it if it does not exist.
+//
+// Parameters:
+//   - slaveID: The Modbus slave ID.
+//
+// Returns:
+//   - A pointer to the Slave.
+func (s *Simulator) Slave(slaveID byte) *Slave {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	slave, ok := s.slaves[slaveID]
+	if !ok {
+		slave = newSlave()
+		s.slaves[slaveID] = slave
+	}
+	return slave
+}
+
+// Start listens on a random local TCP port and serves clients in the
+// background until the simulator is closed.
+//
+// Returns:
+//   - address: The address to connect clients to (e.g., "127.0.0.1:41234").
+//   - err: An error if listening fails.
+func (s *Simulator) Start() (address string, err error) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return "", err
+	}
+	go s.Serve(listener)
+	return listener.Addr().String(), nil
+}
+
+// ListenAndServe listens on a TCP address and serves clients until the simulator is closed.
+//
+// Parameters:
+//   - address: The address to listen on (e.g., ":8899").
+//
+// Returns:
+//   - An error if listening fails, or net.ErrClosed after Close.
+func (s *Simulator) ListenAndServe(address string) error {
+	listener, err := net.Listen("tcp", address)
+	if err != nil {
+		return err
+	}
+	return s.Serve(listener)
+}
+
+// Serve accepts clients on listener until the simulator is closed.
+//
+// Parameters:
+//   - listener: The listener to accept clients on. It is closed by Serve.
+//
+// Returns:
+//   - An error if accepting fails, or net.ErrClosed after Close.
+func (s *Simulator) Serve(listener net.Listener) error {
+	return s.server.serve(listener, s.serveConn)
+}
+
+// Close stops accepting clients, disconnects all clients and cancels delayed responses.
+//
+// Returns:
+//   - An error if closing a listener fails.
+func (s *Simulator) Close() error {
+	return s.server.close()
+}
+
+// Handle answers one request frame.
+//
+// Parameters:
+//   - frame: The request frame.
+//
+// Returns:
+//   - response: The response frame, or nil if the request is ignored.
+//   - err: The reason why the request is ignored.
+func (s *Simulator) Handle(frame []byte) (response []byte, err error) {
+	request, err := gosolarman.ParseRequest(frame)
+	if err != nil {
+		return nil, err
+	}
+	if request.Header.ControlCode != gosolarman.ControlCodeRequest {
+		return nil, &gosolarman.FrameError{Err: gosolarman.ErrControlCode, Expected: gosolarman.ControlCodeRequest, Got: uint32(request.Header.ControlCode), Frame: frame}
+	}
+	if request.Header.LoggerSerialNumber != s.LoggerSerial {
+		return nil, &gosolarman.FrameError{Err: gosolarman.ErrLoggerSerialMismatch, Expected: s.LoggerSerial, Got: request.Header.LoggerSerialNumber, Frame: frame}
+	}
+
+	header := &gosolarman.Header{
+		ControlCode:        gosolarman.ControlCodeResponse,
+		SequenceNumber:     request.Header.SequenceNumber,
+		LoggerSerialNumber: s.LoggerSerial,
+	}
+	s.mu.Lock()
+	slave, ok := s.slaves[request.Payload.SlaveID]
+	if s.replaceSequence {
+		s.counter++
+		header.SequenceNumber = header.SequenceNumber&0x00FF | uint16(s.counter)<<8
+	}
+	s.mu.Unlock()
+	if !ok {
+		// The inverter does not answer, so neither does the data logging stick.
+		fixed := s.responsePayload(request.Payload.FrameType, 0, nil)
+		return (&gosolarman.Frame{Header: header, Payload: &gosolarman.UnreachablePayload{
+			FrameType:        fixed.FrameType,
+			Status:           fixed.Status,
+			TotalWorkingTime: fixed.TotalWorkingTime,
+			PowerOnTime:      fixed.PowerOnTime,
+			OffsetTime:       fixed.OffsetTime,
+		}}).Marshal()
+	}
+
+	pdu := execute(slave, &request.Payload.ModbusRTUFrame)
+	return (&gosolarman.Response{
+		Header:  header,
+		Payload: s.responsePayload(request.Payload.FrameType, request.Payload.SlaveID, pdu),
+	}).Marshal()
+}
+
+// responsePayload creates the payload of a response with the current timing
+// fields of the simulated data logging stick.
+//
+// Parameters:
+//   - frameType: The frame type of the request.
+//   - slaveID: The Modbus slave ID.
+//   - pdu: The response PDU, or nil for none.
+//
+// Returns:
+//   - The response payload.
+func (s *Simulator) responsePayload(frameType byte, slaveID byte, pdu *modbus.ProtocolDataUnit) *gosolarman.ResponsePayload {
+	now := time.Now()
+	powerOn := uint32(now.Sub(s.started) / time.Second)
+	payload := &gosolarman.ResponsePayload{
+		FrameType:        frameType,
+		Status:           statusOK,
+		TotalWorkingTime: powerOn,
+		PowerOnTime:      powerOn,
+		OffsetTime:       uint32(now.Unix()) - powerOn,
+		SlaveID:          slaveID,
+	}
+	if pdu != nil {
+		payload.ModbusRTUFrame = *pdu
+	}
+	return payload
+}
+
+// serveConn answers the requests of one client until it disconnects.
+//
+// Parameters:
+//   - ctx: The context of the simulator, cancelled on Close.
+//   - conn: The connection of the client.
+func (s *Simulator) serveConn(ctx context.Context, conn net.Conn) {
+	client := slog.String("client", conn.RemoteAddr().String())
+	s.log(slog.LevelDebug, "client connected", client)
+	defer s.log(slog.LevelDebug, "client disconnected", client)
+
+	reader := newFrameReader(conn)
+	for {
+		frame, err := reader.ReadFrame()
+		if err != nil {
+			return
+		}
+		response, err := s.Handle(frame)
+		if err != nil {
+			s.log(slog.LevelWarn, "ignored request", client, slog.Any("error", err))
+			continue
+		}
+		if !s.respond(ctx, conn, response) {
+			return
+		}
+	}
+}
+
+// log writes a record to the StructuredLogger, if configured.
+//
+// Parameters:
+//   - level: The level of the record.
+//   - msg: The message of the record.
+//   - attrs: The attributes of the record.
+func (s *Simulator) log(level slog.Level, msg string, attrs ...slog.Attr) {
+	if s.StructuredLogger != nil {
+		s.StructuredLogger.LogAttrs(context.Background(), level, msg, attrs...)
+	}
+}
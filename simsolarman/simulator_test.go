@@ -0,0 +1,194 @@
+package simsolarman
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/grid-x/modbus"
+	"github.com/tlmnb/gosolarman"
+)
+
+// startSimulator starts a simulator with one slave and connects a client to it.
+//
+// Parameters:
+//   - t: The test.
+//   - loggerSerial: The logger serial number used by the client.
+//   - slaveID: The slave ID used by the client.
+//
+// Returns:
+//   - The simulator, with slave 1 holding registers 0-9 and input registers 100-101.
+//   - The client connected to the simulator.
+func startSimulator(t *testing.T, loggerSerial uint32, slaveID byte) (*Simulator, *gosolarman.ContextClient) {
+	t.Helper()
+	simulator := NewSimulator(0x12345678)
+	slave := simulator.Slave(0x01)
+	slave.SetHoldingRegisters(0, make([]uint16, 10)...)
+	slave.SetInputRegisters(100, 0x1234, 0x5678)
+	address, err := simulator.Start()
+	if err != nil {
+		t.Fatalf("Start failed: %v", err)
+	}
+	t.Cleanup(func() { simulator.Close() })
+
+	handler := gosolarman.NewSolarmanClientHandler(address, loggerSerial)
+	handler.SlaveID = slaveID
+	handler.Timeout = time.Second
+	t.Cleanup(func() { handler.Close() })
+	return simulator, gosolarman.NewContextClient(handler)
+}
+
+func TestSimulatorReadWrite(t *testing.T) {
+	simulator, client := startSimulator(t, 0x12345678, 0x01)
+	ctx := context.Background()
+
+	results, err := client.ReadInputRegistersContext(ctx, 100, 2)
+	if err != nil {
+		t.Fatalf("ReadInputRegistersContext failed: %v", err)
+	}
+	if expected := []byte{0x12, 0x34, 0x56, 0x78}; !bytes.Equal(results, expected) {
+		t.Errorf("Expected %X, got %X", expected, results)
+	}
+
+	if _, err := client.WriteSingleRegisterContext(ctx, 2, 0xABCD); err != nil {
+		t.Fatalf("WriteSingleRegisterContext failed: %v", err)
+	}
+	if _, err := client.WriteMultipleRegistersContext(ctx, 3, 2, []byte{0x00, 0x01, 0x00, 0x02}); err != nil {
+		t.Fatalf("WriteMultipleRegistersContext failed: %v", err)
+	}
+	results, err = client.ReadHoldingRegistersContext(ctx, 1, 4)
+	if err != nil {
+		t.Fatalf("ReadHoldingRegistersContext failed: %v", err)
+	}
+	if expected := []byte{0x00, 0x00, 0xAB, 0xCD, 0x00, 0x01, 0x00, 0x02}; !bytes.Equal(results, expected) {
+		t.Errorf("Expected %X, got %X", expected, results)
+	}
+
+	values, _ := simulator.Slave(0x01).HoldingRegisters(2, 3)
+	if expected := []uint16{0xABCD, 0x0001, 0x0002}; !slices.Equal(values, expected) {
+		t.Errorf("Expected %04X, got %04X", expected, values)
+	}
+}
+
+func TestSimulatorExceptions(t *testing.T) {
+	_, client := startSimulator(t, 0x12345678, 0x01)
+	ctx := context.Background()
+
+	tests := []struct {
+		name     string
+		send     func() ([]byte, error)
+		expected byte
+	}{
+		{"undefined register", func() ([]byte, error) { return client.ReadHoldingRegistersContext(ctx, 8, 4) }, modbus.ExceptionCodeIllegalDataAddress},
+		{"input register write", func() ([]byte, error) { return client.WriteSingleRegisterContext(ctx, 100, 1) }, modbus.ExceptionCodeIllegalDataAddress},
+		{"unsupported function", func() ([]byte, error) { return client.ReadCoilsContext(ctx, 0, 1) }, modbus.ExceptionCodeIllegalFunction},
+	}
+	for _, test := range tests {
+		_, err := test.send()
+		var exception *gosolarman.ExceptionError
+		if !errors.As(err, &exception) {
+			t.Errorf("%s: expected ExceptionError, got %v", test.name, err)
+			continue
+		}
+		if exception.ExceptionCode != test.expected {
+			t.Errorf("%s: expected exception code %d, got %d", test.name, test.expected, exception.ExceptionCode)
+		}
+	}
+}
+
+func TestSimulatorUnknownSlave(t *testing.T) {
+	_, client := startSimulator(t, 0x12345678, 0x02)
+
+	_, err := client.ReadHoldingRegistersContext(context.Background(), 0, 1)
+	if !errors.Is(err, gosolarman.ErrInverterUnreachable) {
+		t.Errorf("Expected ErrInverterUnreachable, got %v", err)
+	}
+}
+
+func TestSimulatorWrongLoggerSerial(t *testing.T) {
+	_, client := startSimulator(t, 0x87654321, 0x01)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+	defer cancel()
+	if _, err := client.ReadHoldingRegistersContext(ctx, 0, 1); err == nil {
+		t.Error("Expected the request to time out, got a response")
+	}
+}
+
+func TestSimulatorHandle(t *testing.T) {
+	simulator := NewSimulator(0x12345678)
+	simulator.Slave(0x01).SetHoldingRegisters(0x0010, 0x0102)
+
+	request, err := (&gosolarman.Request{
+		Header: &gosolarman.Header{ControlCode: gosolarman.ControlCodeRequest, SequenceNumber: 0x0A0B, LoggerSerialNumber: 0x12345678},
+		Payload: &gosolarman.RequestPayload{
+			FrameType:      0x02,
+			SlaveID:        0x01,
+			ModbusRTUFrame: modbus.ProtocolDataUnit{FunctionCode: modbus.FuncCodeReadHoldingRegisters, Data: []byte{0x00, 0x10, 0x00, 0x01}},
+		},
+	}).Marshal()
+	if err != nil {
+		t.Fatalf("Marshal failed: %v", err)
+	}
+
+	frame, err := simulator.Handle(request)
+	if err != nil {
+		t.Fatalf("Handle failed: %v", err)
+	}
+	response, err := gosolarman.Parse(frame)
+	if err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+	if response.Header.ControlCode != gosolarman.ControlCodeResponse || response.Header.SequenceNumber != 0x0A0B {
+		t.Errorf("Expected response to sequence 0x0A0B, got control code 0x%04X and sequence 0x%04X", response.Header.ControlCode, response.Header.SequenceNumber)
+	}
+	if expected := []byte{0x02, 0x01, 0x02}; !bytes.Equal(response.Payload.ModbusRTUFrame.Data, expected) {
+		t.Errorf("Expected %X, got %X", expected, response.Payload.ModbusRTUFrame.Data)
+	}
+
+	simulator.LoggerSerial = 0x87654321
+	if _, err := simulator.Handle(request); !errors.Is(err, gosolarman.ErrLoggerSerialMismatch) {
+		t.Errorf("Expected ErrLoggerSerialMismatch, got %v", err)
+	}
+}
+
+func TestSimulatorReplaceSequence(t *testing.T) {
+	simulator := NewSimulator(0x12345678)
+	simulator.Slave(0x01).SetHoldingRegisters(0, 0x0102)
+	simulator.replaceSequence = true
+
+	request, err := (&gosolarman.Request{
+		Header: &gosolarman.Header{ControlCode: gosolarman.ControlCodeRequest, SequenceNumber: 0x0A0B, LoggerSerialNumber: 0x12345678},
+		Payload: &gosolarman.RequestPayload{
+			FrameType:      0x02,
+			SlaveID:        0x01,
+			ModbusRTUFrame: modbus.ProtocolDataUnit{FunctionCode: modbus.FuncCodeReadHoldingRegisters, Data: []byte{0x00, 0x00, 0x00, 0x01}},
+		},
+	}).Marshal()
+	if err != nil {
+		t.Fatalf("Marshal failed: %v", err)
+	}
+	for _, counter := range []byte{0x01, 0x02} {
+		response, err := simulator.Handle(request)
+		if err != nil {
+			t.Fatalf("Handle failed: %v", err)
+		}
+		if response[5] != 0x0B || response[6] != counter {
+			t.Errorf("Expected sequence bytes 0B%02X, got %X", counter, response[5:7])
+		}
+	}
+
+	// Clients of such sticks need LooseSequence.
+	handler, client := connect(t, simulator)
+	handler.LooseSequence = true
+	results, err := client.ReadHoldingRegistersContext(context.Background(), 0, 1)
+	if err != nil {
+		t.Fatalf("ReadHoldingRegistersContext failed: %v", err)
+	}
+	if !bytes.Equal(results, []byte{0x01, 0x02}) {
+		t.Errorf("Expected 0102, got %X", results)
+	}
+}
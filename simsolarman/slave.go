@@ -0,0 +1,132 @@
+package simsolarman
+
+import "sync"
+
+// Slave holds the register banks of one simulated Modbus slave behind the
+// data logging stick. It is safe for concurrent use.
+type Slave struct {
+	mu      sync.Mutex
+	holding map[uint16]uint16 // Holding registers by address.
+	input   map[uint16]uint16 // Input registers by address.
+}
+
+// newSlave creates a new slave without registers.
+//
+// Returns:
+//   - A pointer to the created Slave.
+func newSlave() *Slave {
+	return &Slave{
+		holding: make(map[uint16]uint16),
+		input:   make(map[uint16]uint16),
+	}
+}
+
+// SetHoldingRegisters defines consecutive holding registers starting at address.
+// Reading or writing a holding register that has not been defined is answered
+// with an illegal data address exception.
+//
+// Parameters:
+//   - address: The address of the first register.
+//   - values: The values of the registers.
+func (s *Slave) SetHoldingRegisters(address uint16, values ...uint16) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	setRegisters(s.holding, address, values)
+}
+
+// SetInputRegisters defines consecutive input registers starting at address.
+// Reading an input register that has not been defined is answered with an
+// illegal data address exception.
+//
+// Parameters:
+//   - address: The address of the first register.
+//   - values: The values of the registers.
+func (s *Slave) SetInputRegisters(address uint16, values ...uint16) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	setRegisters(s.input, address, values)
+}
+
+// HoldingRegisters returns the values of consecutive holding registers.
+//
+// Parameters:
+//   - address: The address of the first register.
+//   - quantity: The number of registers.
+//
+// Returns:
+//   - values: The values of the registers.
+//   - ok: false if any of the registers is not defined.
+func (s *Slave) HoldingRegisters(address, quantity uint16) (values []uint16, ok bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return getRegisters(s.holding, address, quantity)
+}
+
+// InputRegisters returns the values of consecutive input registers.
+//
+// Parameters:
+//   - address: The address of the first register.
+//   - quantity: The number of registers.
+//
+// Returns:
+//   - values: The values of the registers.
+//   - ok: false if any of the registers is not defined.
+func (s *Slave) InputRegisters(address, quantity uint16) (values []uint16, ok bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return getRegisters(s.input, address, quantity)
+}
+
+// writeHoldingRegisters overwrites consecutive holding registers if all of
+// them are defined.
+//
+// Parameters:
+//   - address: The address of the first register.
+//   - values: The new values of the registers.
+//
+// Returns:
+//   - false if any of the registers is not defined.
+func (s *Slave) writeHoldingRegisters(address uint16, values []uint16) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := getRegisters(s.holding, address, uint16(len(values))); !ok {
+		return false
+	}
+	setRegisters(s.holding, address, values)
+	return true
+}
+
+// setRegisters stores values at consecutive addresses of a register bank.
+//
+// Parameters:
+//   - bank: The register bank.
+//   - address: The address of the first register.
+//   - values: The values of the registers.
+func setRegisters(bank map[uint16]uint16, address uint16, values []uint16) {
+	for i, value := range values {
+		bank[address+uint16(i)] = value
+	}
+}
+
+// getRegisters loads consecutive registers of a register bank.
+//
+// Parameters:
+//   - bank: The register bank.
+//   - address: The address of the first register.
+//   - quantity: The number of registers.
+//
+// Returns:
+//   - values: The values of the registers.
+//   - ok: false if any of the registers is not defined or the range exceeds the address space.
+func getRegisters(bank map[uint16]uint16, address, quantity uint16) (values []uint16, ok bool) {
+	if int(address)+int(quantity) > 0x10000 {
+		return nil, false
+	}
+	values = make([]uint16, quantity)
+	for i := range values {
+		if values[i], ok = bank[address+uint16(i)]; !ok {
+			return nil, false
+		}
+	}
+	return values, true
+}
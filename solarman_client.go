@@ -1,14 +1,16 @@
 package gosolarman
 
 import (
-	"bytes"
+	"context"
 	"encoding/binary"
-	"encoding/hex"
 	"errors"
 	"fmt"
+	"log/slog"
+	"math/rand/v2"
 	"net"
+	"os"
 	"sync"
-	"syscall"
+	"sync/atomic"
 	"time"
 
 	"github.com/grid-x/modbus"
@@ -24,10 +26,28 @@ const (
 	// ControlCodeRequest is the control code for Modbus RTU requests in client mode.
 	ControlCodeRequest = 0x4510
 
-	// FrameType denotes the frame type for outgoing Modbus RTU requests (e.g., 0x02 for solar inverter).
+	// ControlCodeResponse is the control code for Modbus RTU responses in client mode.
+	ControlCodeResponse = 0x1510
+
+	// ControlCodeHandshake is the control code of handshake frames sent by the data logging stick.
+	ControlCodeHandshake = 0x4110
+
+	// ControlCodeData is the control code of data frames sent by the data logging stick.
+	ControlCodeData = 0x4210
+
+	// ControlCodeInfo is the control code of info frames sent by the data logging stick.
+	ControlCodeInfo = 0x4310
+
+	// ControlCodeHeartbeat is the control code of heartbeat frames sent by the data logging stick.
+	ControlCodeHeartbeat = 0x4710
+
+	// ControlCodeReport is the control code of report frames sent by the data logging stick.
+	ControlCodeReport = 0x4810
+
+	// FrameType denotes the default frame type for outgoing Modbus RTU requests (e.g., 0x02 for solar inverter).
 	FrameType = 0x02
 
-	// SensorType denotes the sensor type for outgoing requests (e.g., 0x0000).
+	// SensorType denotes the default sensor type for outgoing requests (e.g., 0x0000).
 	SensorType = 0x0000
 
 	// TotalWorkingTime is the total working time of the data logging stick (set to 0x00000000 for outgoing requests).
@@ -39,32 +59,181 @@ const (
 	// OffsetTime is the offset timestamp (set to 0x00000000 for outgoing requests).
 	OffsetTime = 0x00000000
 
-	Timeout = 5 * time.Second 
+	Timeout = 5 * time.Second
 )
 
 // SolarmanClientHandler is a handler that combines the Solarman packager and transporter.
+// It is safe for concurrent use by multiple goroutines. Its exported fields
+// must only be set before the first request; SetSlave and SetDeviceProfile
+// may be called at any time.
 type SolarmanClientHandler struct {
 	solarmanPackager
 	solarmanTransporter
 }
 
 // NewSolarmanClientHandler creates a new Solarman client handler.
+// Prefix the address with "udp://" to exchange frames over UDP instead of TCP.
 //
 // Parameters:
-//   - Address: The address of the Solarman device (e.g., "192.168.1.1:8899").
+//   - Address: The address of the Solarman device (e.g., "192.168.1.1:8899" or "udp://192.168.1.1:8899").
 //   - LoggerSerial: The serial number of the data logging stick.
 //
 // Returns:
 //   - A pointer to the created SolarmanClientHandler.
 func NewSolarmanClientHandler(Address string, LoggerSerial uint32) *SolarmanClientHandler {
 	handler := &SolarmanClientHandler{}
-	handler.Address = Address
+	handler.Network, handler.Address = splitAddress(Address)
 	handler.LoggerSerial = LoggerSerial
+	handler.DeviceProfile = DefaultDeviceProfile
+	handler.SetSequence(randomSequence())
 	handler.Timeout = Timeout
 	handler.ConnectDelay = 0
+	if handler.datagram() {
+		handler.RetransmitInterval = RetransmitInterval
+	}
 	return handler
 }
 
+// Send sends a request frame and receives the verified response.
+//
+// Parameters:
+//   - aduRequest: The request frame to send.
+//
+// Returns:
+//   - aduResponse: The response frame received from the device.
+//   - err: An error if the operation fails.
+func (h *SolarmanClientHandler) Send(aduRequest []byte) (aduResponse []byte, err error) {
+	return h.SendContext(context.Background(), aduRequest)
+}
+
+// SendContext sends a request frame and receives the response, which is
+// verified with the settings of the packager (e.g., LooseSequence) within
+// the retry loop of the transporter.
+//
+// Parameters:
+//   - ctx: The context controlling the exchange.
+//   - aduRequest: The request frame to send.
+//
+// Returns:
+//   - aduResponse: The response frame received from the device.
+//   - err: An error if the operation fails.
+func (h *SolarmanClientHandler) SendContext(ctx context.Context, aduRequest []byte) (aduResponse []byte, err error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.checker = h
+	return h.send(ctx, aduRequest)
+}
+
+// sendPipelined sends requests with up to PipelineWindow of them outstanding
+// and passes each verified response to deliver.
+//
+// Parameters:
+//   - ctx: The context controlling the exchanges.
+//   - aduRequests: The request frames to send.
+//   - deliver: Called with the index and the response or error of each request.
+func (h *SolarmanClientHandler) sendPipelined(ctx context.Context, aduRequests [][]byte, deliver func(i int, aduResponse []byte, err error)) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.checker = h
+	h.solarmanTransporter.sendPipelined(ctx, aduRequests, deliver)
+}
+
+// Verify verifies that a Modbus RTU response matches the corresponding request
+// and logs and counts verification failures.
+//
+// Parameters:
+//   - aduRequest: The Modbus RTU request.
+//   - aduResponse: The Modbus RTU response.
+//
+// Returns:
+//   - err: An error if the verification fails.
+func (h *SolarmanClientHandler) Verify(aduRequest []byte, aduResponse []byte) (err error) {
+	if err = h.solarmanPackager.Verify(aduRequest, aduResponse); err != nil {
+		h.frameError(context.Background(), "verify failed", aduResponse, err)
+	}
+	return err
+}
+
+// Decode decodes an Application Data Unit (ADU) into a Modbus Protocol Data Unit (PDU)
+// and logs and counts decoding failures.
+//
+// Parameters:
+//   - adu: The Application Data Unit to decode.
+//
+// Returns:
+//   - pdu: The decoded Modbus Protocol Data Unit.
+//   - err: An error if the decoding fails.
+func (h *SolarmanClientHandler) Decode(adu []byte) (pdu *modbus.ProtocolDataUnit, err error) {
+	if pdu, err = h.solarmanPackager.Decode(adu); err != nil {
+		h.frameError(context.Background(), "decode failed", adu, err)
+	}
+	return pdu, err
+}
+
+// verifyResponse checks a response within the retry loop of the transporter,
+// so that invalid frames are classified as protocol errors and retried
+// according to the RetryPolicy. Besides Verify, it checks the CRC of the
+// Modbus RTU frame; other decoding errors (e.g., an unreachable inverter) are
+// left to Decode. As every client sends through the transporter, this is
+// where the verify and decode spans are created.
+//
+// Parameters:
+//   - ctx: The context of the exchange.
+//   - aduRequest: The Modbus RTU request.
+//   - aduResponse: The Modbus RTU response.
+//
+// Returns:
+//   - err: A FrameError if the response is invalid.
+func (h *SolarmanClientHandler) verifyResponse(ctx context.Context, aduRequest []byte, aduResponse []byte) (err error) {
+	_, span := h.startSpan(ctx, SpanVerify)
+	err = h.solarmanPackager.Verify(aduRequest, aduResponse)
+	span.End(err)
+	if err != nil {
+		h.frameError(ctx, "verify failed", aduResponse, err)
+		return fmt.Errorf("invalid response from %q: %w", h.Address, err)
+	}
+
+	_, span = h.startSpan(ctx, SpanDecode)
+	_, err = Parse(aduResponse)
+	var frameErr *FrameError
+	if !errors.As(err, &frameErr) {
+		err = nil
+	}
+	span.End(err)
+	if err != nil {
+		h.frameError(ctx, "decode failed", aduResponse, err)
+		return fmt.Errorf("failed to decode response from %q: %w", h.Address, err)
+	}
+	return nil
+}
+
+// looseSequence reports whether responses echo only the first sequence number byte.
+//
+// Returns:
+//   - The LooseSequence setting of the packager.
+func (h *SolarmanClientHandler) looseSequence() bool {
+	return h.LooseSequence
+}
+
+// frameError logs and counts an invalid response.
+//
+// Parameters:
+//   - ctx: The context of the exchange.
+//   - msg: The log message (e.g., "verify failed").
+//   - frame: The invalid response.
+//   - err: The verification or decoding error.
+func (h *SolarmanClientHandler) frameError(ctx context.Context, msg string, frame []byte, err error) {
+	if h.Metrics != nil {
+		h.Metrics.ObserveFrameError(FrameErrorKind(err))
+	}
+	if h.logEnabled(ctx, slog.LevelWarn) {
+		attrs := append(frameAttrs(frame), slog.Int("slave_id", int(h.slaveID())), slog.Any("error", err))
+		h.log(ctx, slog.LevelWarn, msg, attrs...)
+	}
+}
+
 // NewSolarmanClient creates a new Modbus client for Solarman devices.
 //
 // Parameters:
@@ -82,11 +251,99 @@ func NewSolarmanClient(Address string, LoggerSerial uint32, SlaveID byte) modbus
 // solarmanTransporter handles the transport layer for Solarman communication.
 type solarmanTransporter struct {
 	Address      string        // Address of the Solarman device.
+	Network      string        // Network of Address, NetworkTCP (default) or NetworkUDP.
 	mu           sync.Mutex    // Mutex for thread-safe access to the connection.
-	conn         net.Conn      // TCP connection to the Solarman device.
+	conn         net.Conn      // Connection to the Solarman device.
+	reader       *frameReader  // Frame reader on top of conn.
 	Logger       modbus.Logger // Logger for debugging and monitoring.
 	Timeout      time.Duration // Timeout for read/write operations.
 	ConnectDelay time.Duration // Delay before attempting first access to the device.
+	RetryPolicy  RetryPolicy   // Policy for retrying failed exchanges (DefaultRetryPolicy if nil).
+
+	// PipelineWindow is the maximum number of requests that
+	// ContextClient.SendPipelinedContext keeps outstanding. With 1 or less,
+	// pipelined requests are sent one at a time.
+	PipelineWindow int
+
+	// RetransmitInterval is the time after which a request over UDP is sent
+	// again if no response arrived, until Timeout expires. Zero disables
+	// retransmission.
+	RetransmitInterval time.Duration
+
+	// Dialer establishes the connection to Address. It is used with
+	// DialContext if it implements ContextDialer. If nil, a TCP connection
+	// is dialed with a net.Dialer limited by Timeout.
+	Dialer Dialer
+
+	// StructuredLogger receives leveled, structured records of dials, sent
+	// and received frames, retries and verification failures.
+	StructuredLogger *slog.Logger
+
+	// Metrics receives measurements of requests, reconnects and invalid or
+	// unsolicited frames.
+	Metrics Metrics
+
+	// Tracer creates spans for sending requests, dialing, writing and reading.
+	Tracer Tracer
+
+	dialed bool // Whether a connection has been established before.
+
+	pipelineUnsupported bool // Whether the device failed to answer pipelined requests.
+	serialSuccesses     int  // Successful requests sent one at a time since pipelining failed.
+
+	// OnUnsolicitedFrame is called with every frame that the data logging stick
+	// pushes on the connection while waiting for a response (e.g., heartbeats).
+	// It is called with the transporter locked and must not send requests.
+	OnUnsolicitedFrame func(frame []byte)
+
+	// checker verifies the responses. The handler sets it to itself
+	// whenever it sends, so that it also applies to handlers that were not
+	// created by NewSolarmanClientHandler. Without it, responses are verified
+	// with the default settings of the packager.
+	checker responseChecker
+}
+
+// responseChecker verifies the responses of a transporter.
+type responseChecker interface {
+	// verifyResponse returns a FrameError if aduResponse is no valid
+	// response to aduRequest.
+	verifyResponse(ctx context.Context, aduRequest []byte, aduResponse []byte) error
+
+	// looseSequence reports whether responses echo only the first sequence
+	// number byte.
+	looseSequence() bool
+}
+
+// defaultChecker verifies responses with the default settings of the packager.
+type defaultChecker struct{}
+
+// verifyResponse verifies a response with the default settings of the packager.
+//
+// Parameters:
+//   - ctx: The context of the exchange.
+//   - aduRequest: The request frame.
+//   - aduResponse: The response frame.
+//
+// Returns:
+//   - err: A FrameError if the response is invalid.
+func (defaultChecker) verifyResponse(ctx context.Context, aduRequest []byte, aduResponse []byte) error {
+	return (&solarmanPackager{}).Verify(aduRequest, aduResponse)
+}
+
+// looseSequence reports that responses echo both sequence number bytes.
+func (defaultChecker) looseSequence() bool {
+	return false
+}
+
+// responseChecker returns the checker of the responses.
+//
+// Returns:
+//   - The checker set by the handler, or defaultChecker if none is set.
+func (mb *solarmanTransporter) responseChecker() responseChecker {
+	if mb.checker == nil {
+		return defaultChecker{}
+	}
+	return mb.checker
 }
 
 // Send sends a Modbus RTU request and receives the response.
@@ -98,37 +355,250 @@ type solarmanTransporter struct {
 //   - aduResponse: The Modbus RTU response received from the device.
 //   - err: An error if the operation fails.
 func (mb *solarmanTransporter) Send(aduRequest []byte) (aduResponse []byte, err error) {
+	return mb.SendContext(context.Background(), aduRequest)
+}
+
+// SendContext sends a Modbus RTU request and receives the response.
+// The exchange is aborted when ctx is cancelled or its deadline expires,
+// and the connection is reset so that a late reply cannot be mistaken for
+// the response to the next request.
+//
+// Parameters:
+//   - ctx: The context controlling the exchange.
+//   - aduRequest: The Modbus RTU request to send.
+//
+// Returns:
+//   - aduResponse: The Modbus RTU response received from the device.
+//   - err: An error if the operation fails.
+func (mb *solarmanTransporter) SendContext(ctx context.Context, aduRequest []byte) (aduResponse []byte, err error) {
 	mb.mu.Lock()
 	defer mb.mu.Unlock()
-	if err = mb.connect(); err != nil {
-		return nil, fmt.Errorf("failed to connect to %q: %w", mb.Address, err)
+
+	return mb.send(ctx, aduRequest)
+}
+
+// send sends a request and receives the response, retrying according to the
+// RetryPolicy. Responses are verified within the loop, so that an invalid
+// frame is retried as a protocol error. The transporter must be locked.
+//
+// Parameters:
+//   - ctx: The context controlling the exchange.
+//   - aduRequest: The Modbus RTU request to send.
+//
+// Returns:
+//   - aduResponse: The Modbus RTU response received from the device.
+//   - err: An error if the operation fails.
+func (mb *solarmanTransporter) send(ctx context.Context, aduRequest []byte) (aduResponse []byte, err error) {
+	policy := mb.retryPolicy()
+	var attempt int
+	ctx, span := mb.startSpan(ctx, SpanSend)
+	span.SetRequest(aduRequest)
+	defer func() {
+		span.SetAttempts(attempt)
+		span.End(err)
+	}()
+	if mb.Metrics != nil {
+		start := time.Now()
+		defer func() {
+			outcome := OutcomeSuccess
+			if err != nil {
+				outcome = policy.Classify(err).String()
+			}
+			mb.Metrics.ObserveRequest(requestFunctionCode(aduRequest), outcome, time.Since(start))
+		}()
 	}
+	for attempt = 1; ; attempt++ {
+		if err = ctx.Err(); err != nil {
+			return nil, err
+		}
+		if attempt > 1 && mb.conn == nil {
+			mb.log(ctx, slog.LevelInfo, "reconnecting", slog.Int("attempt", attempt))
+		}
+		if err = mb.connect(ctx); err != nil {
+			err = fmt.Errorf("%w to %q: %w", errConnect, mb.Address, err)
+		} else if aduResponse, err = mb.exchange(ctx, aduRequest); err == nil {
+			err = mb.responseChecker().verifyResponse(ctx, aduRequest, aduResponse)
+		}
+		if err == nil {
+			if attempt > 1 {
+				mb.log(ctx, slog.LevelInfo, "attempt succeeded", slog.Int("attempt", attempt))
+			}
+			return aduResponse, nil
+		}
 
-	err = mb.write(aduRequest)
+		class := policy.Classify(err)
+		retry, delay, reconnect := policy.Retry(attempt, class)
+		mb.log(ctx, slog.LevelWarn, "attempt failed",
+			slog.Int("attempt", attempt),
+			slog.String("class", class.String()),
+			slog.Bool("retry", retry),
+			slog.Duration("delay", delay),
+			slog.Any("error", err))
+		// A connection that failed for any other reason than a timeout is
+		// unusable, and after a cancelled exchange a late reply may still arrive.
+		if reconnect || ctx.Err() != nil || (class != ErrorClassProtocol && !errors.Is(err, os.ErrDeadlineExceeded)) {
+			if mb.conn != nil {
+				mb.log(ctx, slog.LevelDebug, "closing connection")
+			}
+			mb.close()
+		}
+		if !retry {
+			return nil, err
+		}
+		if err = sleepContext(ctx, delay); err != nil {
+			return nil, err
+		}
+	}
+}
 
-	if errors.Is(err, syscall.EPIPE) {
-		if err = mb.reconnect(); err != nil {
-			return nil, fmt.Errorf("failed to reconnect to %q: %w", mb.Address, err)
+// retryPolicy returns the RetryPolicy of the transporter.
+//
+// Returns:
+//   - The RetryPolicy, or DefaultRetryPolicy if none is set.
+func (mb *solarmanTransporter) retryPolicy() RetryPolicy {
+	if mb.RetryPolicy == nil {
+		return DefaultRetryPolicy
+	}
+	return mb.RetryPolicy
+}
+
+// exchange writes a request and reads the response within the deadline
+// derived from ctx and the transporter's Timeout. Over UDP, the request is
+// sent again every RetransmitInterval until a response arrives.
+//
+// Parameters:
+//   - ctx: The context controlling the exchange.
+//   - aduRequest: The Modbus RTU request to send.
+//
+// Returns:
+//   - aduResponse: The Modbus RTU response received from the device.
+//   - err: An error if the operation fails.
+func (mb *solarmanTransporter) exchange(ctx context.Context, aduRequest []byte) (aduResponse []byte, err error) {
+	conn := mb.conn
+	deadline := mb.deadline(ctx)
+	if err = conn.SetDeadline(deadline); err != nil {
+		return nil, fmt.Errorf("failed to set deadline on %q: %w", mb.Address, err)
+	}
+	// Unblock pending reads and writes as soon as ctx is done.
+	stop := context.AfterFunc(ctx, func() {
+		conn.SetDeadline(time.Unix(1, 0))
+	})
+	defer stop()
+
+	start := time.Now()
+	for transmission := 1; ; transmission++ {
+		_, span := mb.startSpan(ctx, SpanWrite)
+		err = mb.write(aduRequest)
+		span.End(err)
+		if err != nil {
+			return nil, fmt.Errorf("failed to write to %q: %w", mb.Address, contextError(ctx, err))
 		}
-		if err = mb.write(aduRequest); err != nil {
-			return nil, fmt.Errorf("failed to write to %q: %w", mb.Address, err)
+		if mb.logEnabled(ctx, slog.LevelDebug) {
+			mb.log(ctx, slog.LevelDebug, "sent frame", append(frameAttrs(aduRequest), slog.Int("transmission", transmission))...)
+		}
+
+		readDeadline, retransmit := mb.retransmitDeadline(deadline)
+		if retransmit {
+			if err = conn.SetReadDeadline(readDeadline); err != nil {
+				return nil, fmt.Errorf("failed to set deadline on %q: %w", mb.Address, err)
+			}
 		}
+		_, span = mb.startSpan(ctx, SpanRead)
+		aduResponse, err = mb.readResponse(ctx, aduRequest)
+		span.End(err)
+		if err == nil {
+			break
+		}
+		if !retransmit || ctx.Err() != nil || !errors.Is(err, os.ErrDeadlineExceeded) {
+			return nil, err
+		}
+		if err = conn.SetDeadline(deadline); err != nil {
+			return nil, fmt.Errorf("failed to set deadline on %q: %w", mb.Address, err)
+		}
+	}
+	if mb.logEnabled(ctx, slog.LevelDebug) {
+		attrs := append(frameAttrs(aduResponse), slog.Duration("latency", time.Since(start)))
+		mb.log(ctx, slog.LevelDebug, "received frame", attrs...)
 	}
-	mb.logf("SENT %s\n", hex.EncodeToString(aduRequest))
-	aduResponse, err = mb.read()
-	if errors.Is(err, syscall.EPIPE) {
-		if err = mb.reconnect(); err != nil {
-			return nil, fmt.Errorf("failed to reconnect to %q: %w", mb.Address, err)
+	return aduResponse, nil
+}
+
+// readResponse reads frames until the response to a request arrives, skipping
+// unsolicited and unmatched frames.
+//
+// Parameters:
+//   - ctx: The context controlling the exchange.
+//   - aduRequest: The Modbus RTU request that was sent.
+//
+// Returns:
+//   - aduResponse: The Modbus RTU response received from the device.
+//   - err: An error if the operation fails.
+func (mb *solarmanTransporter) readResponse(ctx context.Context, aduRequest []byte) (aduResponse []byte, err error) {
+	for {
+		if aduResponse, err = mb.read(ctx); err != nil {
+			return nil, fmt.Errorf("failed to read from %q: %w", mb.Address, contextError(ctx, err))
 		}
-		if err = mb.write(aduRequest); err != nil {
-			return nil, fmt.Errorf("failed to write to %q: %w", mb.Address, err)
+		if mb.matches(aduRequest, aduResponse) {
+			break
 		}
-		if aduResponse, err = mb.read(); err != nil {
-			return nil, fmt.Errorf("failed to read from %q: %w", mb.Address, err)
+		mb.skip(ctx, aduResponse)
+	}
+	return aduResponse, nil
+}
+
+// matches reports whether frame is the response to request. Datagrams of
+// earlier exchanges may still arrive, so over UDP the logger serial number
+// must match as well.
+//
+// Parameters:
+//   - request: The request frame.
+//   - frame: The received frame.
+//
+// Returns:
+//   - true if frame answers request.
+func (mb *solarmanTransporter) matches(request []byte, frame []byte) bool {
+	return mb.isResponseTo(request, frame) && (!mb.datagram() || sameLoggerSerial(request, frame))
+}
+
+// skip handles a frame that does not answer the pending request, passing
+// unsolicited frames to OnUnsolicitedFrame.
+//
+// Parameters:
+//   - ctx: The context controlling the exchange.
+//   - frame: The received frame.
+func (mb *solarmanTransporter) skip(ctx context.Context, frame []byte) {
+	if isUnsolicited(frame) {
+		if mb.logEnabled(ctx, slog.LevelDebug) {
+			mb.log(ctx, slog.LevelDebug, "skipped unsolicited frame", frameAttrs(frame)...)
 		}
+		if mb.Metrics != nil {
+			mb.Metrics.ObserveUnsolicitedFrame(binary.LittleEndian.Uint16(frame[3:5]))
+		}
+		if mb.OnUnsolicitedFrame != nil {
+			mb.OnUnsolicitedFrame(frame)
+		}
+	} else if mb.logEnabled(ctx, slog.LevelWarn) {
+		mb.log(ctx, slog.LevelWarn, "skipped unmatched frame", frameAttrs(frame)...)
 	}
-	mb.logf("RECD %s\n", hex.EncodeToString(aduResponse))
-	return
+}
+
+// deadline returns the I/O deadline for an exchange, which is the earlier of
+// the context deadline and now plus Timeout.
+//
+// Parameters:
+//   - ctx: The context controlling the exchange.
+//
+// Returns:
+//   - The deadline, or the zero time if there is none.
+func (mb *solarmanTransporter) deadline(ctx context.Context) time.Time {
+	var deadline time.Time
+	if mb.Timeout > 0 {
+		deadline = time.Now().Add(mb.Timeout)
+	}
+	if d, ok := ctx.Deadline(); ok && (deadline.IsZero() || d.Before(deadline)) {
+		deadline = d
+	}
+	return deadline
 }
 
 // write sends a request to the Solarman device.
@@ -151,14 +621,20 @@ func (mb *solarmanTransporter) write(request []byte) error {
 
 // read reads a response from the Solarman device.
 //
+// Parameters:
+//   - ctx: The context controlling the exchange.
+//
 // Returns:
 //   - response: The byte array representing the response.
 //   - err: An error if the operation fails.
-func (mb *solarmanTransporter) read() (response []byte, err error) {
-	raw_response := make([]byte, 1024)
-	n, err := mb.conn.Read(raw_response)
-	response = raw_response[0:n]
-	return
+func (mb *solarmanTransporter) read(ctx context.Context) (response []byte, err error) {
+	if mb.datagram() {
+		return mb.readDatagram(ctx)
+	}
+	if mb.reader == nil {
+		mb.reader = newFrameReader(mb.conn)
+	}
+	return mb.reader.ReadFrame()
 }
 
 // Connect establishes a connection to the Solarman device.
@@ -166,75 +642,97 @@ func (mb *solarmanTransporter) read() (response []byte, err error) {
 // Returns:
 //   - An error if the connection fails.
 func (mb *solarmanTransporter) Connect() error {
+	return mb.ConnectContext(context.Background())
+}
+
+// ConnectContext establishes a connection to the Solarman device.
+//
+// Parameters:
+//   - ctx: The context controlling the dial.
+//
+// Returns:
+//   - An error if the connection fails.
+func (mb *solarmanTransporter) ConnectContext(ctx context.Context) error {
 	mb.mu.Lock()
 	defer mb.mu.Unlock()
 
-	return mb.connect()
+	return mb.connect(ctx)
 }
 
-// connect establishes a TCP connection to the Solarman device.
+// connect establishes a connection to the Solarman device.
+//
+// Parameters:
+//   - ctx: The context controlling the dial.
 //
 // Returns:
 //   - An error if the connection fails.
-func (mb *solarmanTransporter) connect() error {
-	fmt.Println("Connecting to", mb.Address)
+func (mb *solarmanTransporter) connect(ctx context.Context) error {
 	if mb.conn == nil {
-		var conn net.Conn
-		var err error
-		d := net.Dialer{
-			Timeout: mb.Timeout,
-		}
-		if conn, err = d.Dial("tcp", mb.Address); err != nil {
+		start := time.Now()
+		mb.log(ctx, slog.LevelDebug, "dialing")
+		_, span := mb.startSpan(ctx, SpanDial)
+		conn, err := mb.dial(ctx)
+		span.End(err)
+		if err != nil {
+			mb.log(ctx, slog.LevelWarn, "dial failed", slog.Duration("latency", time.Since(start)), slog.Any("error", err))
 			return err
 		}
+		mb.log(ctx, slog.LevelInfo, "connected", slog.Duration("latency", time.Since(start)))
+		if mb.dialed && mb.Metrics != nil {
+			mb.Metrics.ObserveReconnect()
+		}
+		mb.dialed = true
 		mb.conn = conn
+		return sleepContext(ctx, mb.ConnectDelay)
 	}
-	time.Sleep(mb.ConnectDelay)
 	return nil
 }
 
-// reconnect closes the existing connection and establishes a new one.
+// Close closes the connection to the Solarman device.
 //
 // Returns:
-//   - An error if the reconnection fails.
-func (mb *solarmanTransporter) reconnect() error {
-	if mb.conn == nil {
-		return mb.connect()
-	} else {
-		mb.conn.Close()
+//   - An error if the operation fails.
+func (mb *solarmanTransporter) Close() (err error) {
+	mb.mu.Lock()
+	defer mb.mu.Unlock()
 
-		mb.conn = nil
-		return mb.connect()
-	}
+	return mb.close()
 }
 
-// Close closes the connection to the Solarman device.
+// close closes the connection and drops any buffered data.
 //
 // Returns:
 //   - An error if the operation fails.
-func (mb *solarmanTransporter) Close() (err error) {
+func (mb *solarmanTransporter) close() (err error) {
 	if mb.conn != nil {
 		err = mb.conn.Close()
+		mb.conn = nil
+		mb.reader = nil
 	}
 	return
 }
 
-// logf logs a formatted message if a logger is configured.
-//
-// Parameters:
-//   - format: The format string.
-//   - v: The values to format.
-func (mb *solarmanTransporter) logf(format string, v ...any) {
-	if mb.Logger != nil {
-		mb.Logger.Printf(format, v)
-	}
-}
-
 // solarmanPackager handles the encoding and decoding of Modbus RTU frames for Solarman devices.
+// It is safe for concurrent use. The exported fields must only be set before
+// the first request; SetSlave and SetDeviceProfile may be called at any time.
 type solarmanPackager struct {
-	SlaveID      byte   // The Modbus slave ID.
-	LoggerSerial uint32 // The serial number of the data logging stick.
-	serial       byte   // The sequence number for requests.
+	DeviceProfile               // The fields of outgoing request payloads.
+	SlaveID       byte          // The Modbus slave ID.
+	LoggerSerial  uint32        // The serial number of the data logging stick.
+	sequence      atomic.Uint32 // The last sequence number used for requests.
+
+	profileMu sync.RWMutex // Guards DeviceProfile and SlaveID against the setters.
+
+	lastFrameInfo atomic.Pointer[FrameInfo] // The logger metadata of the last response.
+
+	// LooseSequence compares only the first sequence number byte of a
+	// response with the request, for data logging sticks that replace the
+	// second byte with their own frame counter. By default both bytes must match.
+	LooseSequence bool
+
+	// OnFrameInfo is called with the logger metadata of every decoded response,
+	// including responses that report an unreachable inverter.
+	OnFrameInfo func(info FrameInfo)
 }
 
 // NewSolarmanPackager creates a new Solarman packager.
@@ -245,10 +743,12 @@ type solarmanPackager struct {
 // Returns:
 //   - A Modbus packager for Solarman devices.
 func NewSolarmanPackager(LoggerSerial uint32) modbus.Packager {
-	return &solarmanPackager{
-		LoggerSerial: LoggerSerial,
-		serial:       0x00,
+	packager := &solarmanPackager{
+		DeviceProfile: DefaultDeviceProfile,
+		LoggerSerial:  LoggerSerial,
 	}
+	packager.SetSequence(randomSequence())
+	return packager
 }
 
 // SetSlave sets the Modbus slave ID.
@@ -256,9 +756,21 @@ func NewSolarmanPackager(LoggerSerial uint32) modbus.Packager {
 // Parameters:
 //   - slaveID: The Modbus slave ID to set.
 func (mb *solarmanPackager) SetSlave(slaveID byte) {
+	mb.profileMu.Lock()
+	defer mb.profileMu.Unlock()
 	mb.SlaveID = slaveID
 }
 
+// slaveID returns the Modbus slave ID.
+//
+// Returns:
+//   - The Modbus slave ID.
+func (mb *solarmanPackager) slaveID() byte {
+	mb.profileMu.RLock()
+	defer mb.profileMu.RUnlock()
+	return mb.SlaveID
+}
+
 // Encode encodes a Modbus Protocol Data Unit (PDU) into an Application Data Unit (ADU).
 //
 // Parameters:
@@ -268,32 +780,40 @@ func (mb *solarmanPackager) SetSlave(slaveID byte) {
 //   - adu: The encoded Application Data Unit.
 //   - err: An error if the encoding fails.
 func (mb *solarmanPackager) Encode(pdu *modbus.ProtocolDataUnit) (adu []byte, err error) {
-	payload := new(bytes.Buffer)
-	payload.WriteByte(FrameType)
-	payload.Write(uint16ToBytes(SensorType, binary.LittleEndian))
-	payload.Write(uint32ToBytes(TotalWorkingTime, binary.LittleEndian))
-	payload.Write(uint32ToBytes(PowerOnTime, binary.LittleEndian))
-	payload.Write(uint32ToBytes(OffsetTime, binary.LittleEndian))
-
-	payload.WriteByte(mb.SlaveID)
-	payload.WriteByte(pdu.FunctionCode)
-	payload.Write(pdu.Data)
-	payload.Write(CRC(mb.SlaveID, pdu))
-	payloadBytes := payload.Bytes()
-
-	request := new(bytes.Buffer)
-	request.WriteByte(StartByte)
-	request.Write(uint16ToBytes(uint16(len(payloadBytes)), binary.LittleEndian))
-	request.Write(uint16ToBytes(ControlCodeRequest, binary.LittleEndian))
-	request.WriteByte(mb.getNextSerial())
-	request.WriteByte(0x00)
-	request.Write(uint32ToBytes(mb.LoggerSerial, binary.LittleEndian))
-	request.Write(payloadBytes)
-
-	request.WriteByte(CheckSum(request.Bytes()[1:]))
-	request.WriteByte(EndByte)
-
-	return request.Bytes(), nil
+	return mb.encode(pdu, mb.slaveID())
+}
+
+// encode encodes a Modbus Protocol Data Unit (PDU) for the given slave ID.
+//
+// Parameters:
+//   - pdu: The Modbus Protocol Data Unit to encode.
+//   - slaveID: The Modbus slave ID of the RTU frame.
+//
+// Returns:
+//   - adu: The encoded Application Data Unit.
+//   - err: An error if the encoding fails.
+func (mb *solarmanPackager) encode(pdu *modbus.ProtocolDataUnit, slaveID byte) (adu []byte, err error) {
+	mb.profileMu.RLock()
+	profile := mb.DeviceProfile
+	mb.profileMu.RUnlock()
+
+	request := &Request{
+		Header: &Header{
+			ControlCode:        ControlCodeRequest,
+			SequenceNumber:     mb.nextSequence(),
+			LoggerSerialNumber: mb.LoggerSerial,
+		},
+		Payload: &RequestPayload{
+			FrameType:        profile.FrameType,
+			SensorType:       profile.SensorType,
+			TotalWorkingTime: profile.TotalWorkingTime,
+			PowerOnTime:      profile.PowerOnTime,
+			OffsetTime:       profile.OffsetTime,
+			SlaveID:          slaveID,
+			ModbusRTUFrame:   *pdu,
+		},
+	}
+	return request.Marshal()
 }
 
 // Decode decodes an Application Data Unit (ADU) into a Modbus Protocol Data Unit (PDU).
@@ -306,6 +826,7 @@ func (mb *solarmanPackager) Encode(pdu *modbus.ProtocolDataUnit) (adu []byte, er
 //   - err: An error if the decoding fails.
 func (mb *solarmanPackager) Decode(adu []byte) (pdu *modbus.ProtocolDataUnit, err error) {
 	response, err := Parse(adu)
+	mb.recordFrameInfo(response, err)
 	if err != nil {
 		return nil, err
 	}
@@ -322,28 +843,34 @@ func (mb *solarmanPackager) Decode(adu []byte) (pdu *modbus.ProtocolDataUnit, er
 //   - err: An error if the verification fails.
 func (mb *solarmanPackager) Verify(aduRequest []byte, aduResponse []byte) (err error) {
 	// Ensure the response is at least the minimum length (header + checksum)
-	if len(aduResponse) < 11 {
-		return fmt.Errorf("response too short, expected at least 11 bytes, got %d", len(aduResponse))
+	if len(aduResponse) < headerLength+trailerLength {
+		return newFrameError(ErrShortFrame, headerLength+trailerLength, uint32(len(aduResponse)), aduResponse)
 	}
 
-	// Verify the Start byte
+	if len(aduRequest) < headerLength {
+		return newFrameError(ErrShortFrame, headerLength, uint32(len(aduRequest)), aduRequest)
+	}
+
+	// Verify the Start and End bytes
 	if aduResponse[0] != StartByte {
-		return fmt.Errorf("invalid start byte, expected 0x%02X, got 0x%02X", StartByte, aduResponse[0])
+		return newFrameError(ErrBadStartByte, StartByte, uint32(aduResponse[0]), aduResponse)
+	}
+	if end := aduResponse[len(aduResponse)-1]; end != EndByte {
+		return newFrameError(ErrBadEndByte, EndByte, uint32(end), aduResponse)
 	}
 
 	// Verify the checksum
 	calculatedChecksum := CheckSum(aduResponse[1 : len(aduResponse)-2]) // Exclude Start and End bytes
 	providedChecksum := aduResponse[len(aduResponse)-2]
 	if calculatedChecksum != providedChecksum {
-		return fmt.Errorf("checksum mismatch: calculated 0x%02X, provided 0x%02X", calculatedChecksum, providedChecksum)
+		return newFrameError(ErrChecksum, uint32(calculatedChecksum), uint32(providedChecksum), aduResponse)
 	}
 
 	// Verify the sequence number
-	requestSequence := aduRequest[5:6]   // Sequence number in the request
-	responseSequence := aduResponse[5:6] // Sequence number in the response
-	if !bytes.Equal(requestSequence, responseSequence) {
-		return fmt.Errorf("sequence number mismatch: request 0x%04X, response 0x%04X",
-			binary.BigEndian.Uint16(requestSequence), binary.BigEndian.Uint16(responseSequence))
+	requestSequence := binary.LittleEndian.Uint16(aduRequest[5:7])   // Sequence number in the request
+	responseSequence := binary.LittleEndian.Uint16(aduResponse[5:7]) // Sequence number in the response
+	if !mb.sequenceMatches(requestSequence, responseSequence) {
+		return newFrameError(ErrSequenceMismatch, uint32(requestSequence), uint32(responseSequence), aduResponse)
 	}
 
 	// Verify the control code
@@ -351,29 +878,171 @@ func (mb *solarmanPackager) Verify(aduRequest []byte, aduResponse []byte) (err e
 	responseControlCode := binary.LittleEndian.Uint16(aduResponse[3:5])
 	expectedResponseControlCode := requestControlCode - 0x3000 // Response code is request code - 0x3000
 	if responseControlCode != expectedResponseControlCode {
-		return fmt.Errorf("control code mismatch: expected 0x%04X, got 0x%04X",
-			expectedResponseControlCode, responseControlCode)
+		return newFrameError(ErrControlCode, uint32(expectedResponseControlCode), uint32(responseControlCode), aduResponse)
 	}
 
 	// Verify the Logger Serial Number
-	requestLoggerSerial := aduRequest[7:11]
-	responseLoggerSerial := aduResponse[7:11]
-	if !bytes.Equal(requestLoggerSerial, responseLoggerSerial) {
-		return fmt.Errorf("logger serial number mismatch: request 0x%X, response 0x%X",
-			requestLoggerSerial, responseLoggerSerial)
+	requestLoggerSerial := binary.LittleEndian.Uint32(aduRequest[7:11])
+	responseLoggerSerial := binary.LittleEndian.Uint32(aduResponse[7:11])
+	if requestLoggerSerial != responseLoggerSerial {
+		return newFrameError(ErrLoggerSerialMismatch, requestLoggerSerial, responseLoggerSerial, aduResponse)
 	}
 
 	// If all checks pass, return nil
 	return nil
 }
 
-// getNextSerial increments and returns the next sequence number for requests.
+// SetDeviceProfile validates and sets the fields of outgoing request payloads.
+//
+// Parameters:
+//   - profile: The device profile to use (e.g., DeviceProfileInverter).
+//
+// Returns:
+//   - An error if the profile is invalid.
+func (mb *solarmanPackager) SetDeviceProfile(profile DeviceProfile) error {
+	if err := profile.Validate(); err != nil {
+		return err
+	}
+	mb.profileMu.Lock()
+	defer mb.profileMu.Unlock()
+	mb.DeviceProfile = profile
+	return nil
+}
+
+// SetSequence sets the sequence number of the next request.
+//
+// Parameters:
+//   - sequence: The sequence number to use for the next request.
+func (mb *solarmanPackager) SetSequence(sequence uint16) {
+	mb.sequence.Store(uint32(sequence - 1))
+}
+
+// nextSequence atomically allocates the next sequence number for requests.
 //
 // Returns:
 //   - The next sequence number.
-func (mb *solarmanPackager) getNextSerial() byte {
-	mb.serial++
-	return mb.serial
+func (mb *solarmanPackager) nextSequence() uint16 {
+	return uint16(mb.sequence.Add(1))
+}
+
+// sequenceMatches reports whether the sequence number of a response matches the request.
+//
+// Parameters:
+//   - request: The sequence number of the request.
+//   - response: The sequence number of the response.
+//
+// Returns:
+//   - true if both bytes match, or the first byte if LooseSequence is set.
+func (mb *solarmanPackager) sequenceMatches(request, response uint16) bool {
+	if mb.LooseSequence {
+		return byte(request) == byte(response)
+	}
+	return request == response
+}
+
+// randomSequence returns a random initial sequence number.
+//
+// Returns:
+//   - A random sequence number.
+func randomSequence() uint16 {
+	return uint16(rand.Uint32N(0x10000))
+}
+
+// isResponseTo reports whether frame is the response to request, i.e. it has
+// the response control code and echoes the request sequence number.
+//
+// Parameters:
+//   - request: The request frame.
+//   - frame: The received frame.
+//
+// Returns:
+//   - true if frame answers request.
+func (mb *solarmanTransporter) isResponseTo(request []byte, frame []byte) bool {
+	if len(request) < headerLength || len(frame) < headerLength {
+		return true // Leave the rejection of short frames to Verify.
+	}
+	controlCode := binary.LittleEndian.Uint16(frame[3:5])
+	expectedControlCode := binary.LittleEndian.Uint16(request[3:5]) - 0x3000
+	return controlCode == expectedControlCode && mb.sequenceKey(frame) == mb.sequenceKey(request)
+}
+
+// sequenceKey returns the part of the sequence number of a frame that a
+// response echoes: both bytes, or only the first byte if the packager of the
+// handler has LooseSequence set.
+//
+// Parameters:
+//   - frame: The request or response frame.
+//
+// Returns:
+//   - The sequence number key, or 0 for short frames.
+func (mb *solarmanTransporter) sequenceKey(frame []byte) uint16 {
+	if len(frame) < headerLength {
+		return 0
+	}
+	sequence := binary.LittleEndian.Uint16(frame[5:7])
+	if mb.responseChecker().looseSequence() {
+		return sequence & 0xFF
+	}
+	return sequence
+}
+
+// isUnsolicited reports whether frame was pushed by the data logging stick on
+// its own, rather than being a response to a request.
+//
+// Parameters:
+//   - frame: The received frame.
+//
+// Returns:
+//   - true if frame has the control code of a handshake, data, info, heartbeat or report frame.
+func isUnsolicited(frame []byte) bool {
+	if len(frame) < headerLength {
+		return false
+	}
+	switch binary.LittleEndian.Uint16(frame[3:5]) {
+	case ControlCodeHandshake, ControlCodeData, ControlCodeInfo, ControlCodeHeartbeat, ControlCodeReport:
+		return true
+	}
+	return false
+}
+
+// contextError returns the context error if err was caused by ctx being done.
+//
+// Parameters:
+//   - ctx: The context controlling the operation.
+//   - err: The error returned by the operation.
+//
+// Returns:
+//   - ctx.Err() if the context is done, otherwise err.
+func contextError(ctx context.Context, err error) error {
+	if ctxErr := ctx.Err(); ctxErr != nil {
+		return ctxErr
+	}
+	if d, ok := ctx.Deadline(); ok && errors.Is(err, os.ErrDeadlineExceeded) && !time.Now().Before(d) {
+		return context.DeadlineExceeded
+	}
+	return err
+}
+
+// sleepContext pauses for the given duration or until ctx is done.
+//
+// Parameters:
+//   - ctx: The context controlling the pause.
+//   - d: The duration to pause.
+//
+// Returns:
+//   - ctx.Err() if the context is done before the duration elapsed.
+func sleepContext(ctx context.Context, d time.Duration) error {
+	if d <= 0 {
+		return nil
+	}
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
 }
 
 // uint32ToBytes converts a uint32 value to a byte array.
@@ -2,7 +2,11 @@ package gosolarman
 
 import (
 	"bytes"
+	"encoding/binary"
+	"errors"
+	"io"
 	"net"
+	"sync"
 	"testing"
 	"time"
 
@@ -41,7 +45,7 @@ func TestSend(t *testing.T) {
 	}
 
 	aduRequest := []byte{0xA5, 0x0E, 0x00, 0x10, 0x45, 0x01, 0x00, 0x4F, 0xAD, 0x6D, 0xA5}
-	mock.readBuffer.Write([]byte{0xA5, 0x0E, 0x00, 0x10, 0x15, 0x01, 0x00, 0x4F, 0xAD, 0x6D, 0xA5, 0x00, 0x15})
+	mock.readBuffer.Write([]byte{0xA5, 0x00, 0x00, 0x10, 0x15, 0x01, 0x00, 0x4F, 0xAD, 0x6D, 0xA5, 0x34, 0x15})
 
 	aduResponse, err := handler.Send(aduRequest)
 	if err != nil {
@@ -52,12 +56,67 @@ func TestSend(t *testing.T) {
 		t.Errorf("Expected written data %X, got %X", aduRequest, mock.writeBuffer.Bytes())
 	}
 
-	expectedResponse := []byte{0xA5, 0x0E, 0x00, 0x10, 0x15, 0x01, 0x00, 0x4F, 0xAD, 0x6D, 0xA5, 0x00, 0x15}
+	expectedResponse := []byte{0xA5, 0x00, 0x00, 0x10, 0x15, 0x01, 0x00, 0x4F, 0xAD, 0x6D, 0xA5, 0x34, 0x15}
 	if !bytes.Equal(aduResponse, expectedResponse) {
 		t.Errorf("Expected response %X, got %X", expectedResponse, aduResponse)
 	}
 }
 
+func TestSendMatchesSequence(t *testing.T) {
+	aduRequest := []byte{0xA5, 0x0E, 0x00, 0x10, 0x45, 0x01, 0x00, 0x4F, 0xAD, 0x6D, 0xA5}
+	// The second sequence number byte is replaced by the frame counter of the stick.
+	aduResponse := []byte{0xA5, 0x00, 0x00, 0x10, 0x15, 0x01, 0x07, 0x4F, 0xAD, 0x6D, 0xA5, 0x3B, 0x15}
+
+	mock := &mockConn{}
+	mock.readBuffer.Write(aduResponse)
+	handler := &solarmanTransporter{
+		conn:        mock,
+		RetryPolicy: &BackoffRetryPolicy{MaxAttempts: 1},
+	}
+	if _, err := handler.Send(aduRequest); !errors.Is(err, io.EOF) {
+		t.Errorf("Expected the response to be skipped, got %v", err)
+	}
+
+}
+
+func TestHandlerWithoutConstructor(t *testing.T) {
+	handler := &SolarmanClientHandler{}
+	handler.LoggerSerial = 0x12345678
+	handler.SlaveID = 0x01
+	handler.RetryPolicy = &BackoffRetryPolicy{MaxAttempts: 1}
+	aduRequest, err := handler.Encode(&modbus.ProtocolDataUnit{FunctionCode: modbus.FuncCodeReadHoldingRegisters, Data: []byte{0x00, 0x10, 0x00, 0x01}})
+	if err != nil {
+		t.Fatalf("Encode failed: %v", err)
+	}
+
+	// Responses are verified although the handler was not created by NewSolarmanClientHandler.
+	aduResponse := responseFrame(aduRequest, 0x002A)
+	aduResponse[len(aduResponse)-2]++
+	mock := &mockConn{}
+	mock.readBuffer.Write(aduResponse)
+	handler.conn = mock
+	if _, err := handler.Send(aduRequest); !errors.Is(err, ErrChecksum) {
+		t.Errorf("Expected ErrChecksum, got %v", err)
+	}
+
+	// LooseSequence applies as well.
+	aduResponse = responseFrame(aduRequest, 0x002A)
+	aduResponse[6]++
+	aduResponse[len(aduResponse)-2]++
+	handler.Close()
+	mock = &mockConn{}
+	mock.readBuffer.Write(aduResponse)
+	handler.conn = mock
+	handler.LooseSequence = true
+	response, err := handler.Send(aduRequest)
+	if err != nil {
+		t.Fatalf("Send failed with LooseSequence: %v", err)
+	}
+	if !bytes.Equal(response, aduResponse) {
+		t.Errorf("Expected response %X, got %X", aduResponse, response)
+	}
+}
+
 func TestEncode(t *testing.T) {
 	packager := &solarmanPackager{
 		SlaveID:      0x01,
@@ -136,3 +195,89 @@ func TestCheckSum(t *testing.T) {
 		t.Errorf("Expected CheckSum 0x%02X, got 0x%02X", expectedChecksum, calculatedChecksum)
 	}
 }
+
+func TestSendSkipsUnsolicitedFrames(t *testing.T) {
+	mock := &mockConn{}
+	var skipped [][]byte
+	handler := &solarmanTransporter{
+		Address: "192.168.1.1:8899",
+		conn:    mock,
+		OnUnsolicitedFrame: func(frame []byte) {
+			skipped = append(skipped, frame)
+		},
+	}
+
+	aduRequest := []byte{0xA5, 0x00, 0x00, 0x10, 0x45, 0x02, 0x00, 0x4F, 0xAD, 0x6D, 0xA5, 0x00, 0x15}
+	heartbeat := []byte{0xA5, 0x01, 0x00, 0x10, 0x47, 0x07, 0x00, 0x4F, 0xAD, 0x6D, 0xA5, 0x00, 0x00, 0x15}
+	stale := []byte{0xA5, 0x00, 0x00, 0x10, 0x15, 0x01, 0x00, 0x4F, 0xAD, 0x6D, 0xA5, 0x00, 0x15}
+	response := []byte{0xA5, 0x00, 0x00, 0x10, 0x15, 0x02, 0x00, 0x4F, 0xAD, 0x6D, 0xA5, 0x00, 0x15}
+	response[11] = CheckSum(response[1:11])
+	mock.readBuffer.Write(heartbeat)
+	mock.readBuffer.Write(stale)
+	mock.readBuffer.Write(response)
+
+	aduResponse, err := handler.Send(aduRequest)
+	if err != nil {
+		t.Fatalf("Send failed: %v", err)
+	}
+	if !bytes.Equal(aduResponse, response) {
+		t.Errorf("Expected response %X, got %X", response, aduResponse)
+	}
+	if len(skipped) != 1 || !bytes.Equal(skipped[0], heartbeat) {
+		t.Errorf("Expected heartbeat to be passed to OnUnsolicitedFrame, got %X", skipped)
+	}
+}
+
+func TestEncodeConcurrentSequence(t *testing.T) {
+	packager := &solarmanPackager{SlaveID: 0x01, LoggerSerial: 0x12345678}
+	packager.SetSequence(0xFFF0)
+
+	const count = 64
+	sequences := make(chan uint16, count)
+	var wg sync.WaitGroup
+	for range count {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			adu, err := packager.Encode(&modbus.ProtocolDataUnit{FunctionCode: 0x03, Data: []byte{0x00, 0x01, 0x00, 0x01}})
+			if err != nil {
+				t.Errorf("Encode failed: %v", err)
+				return
+			}
+			sequences <- binary.LittleEndian.Uint16(adu[5:7])
+		}()
+	}
+	wg.Wait()
+	close(sequences)
+
+	seen := make(map[uint16]bool)
+	for sequence := range sequences {
+		if seen[sequence] {
+			t.Errorf("Duplicate sequence number 0x%04X", sequence)
+		}
+		seen[sequence] = true
+	}
+	if !seen[0xFFF0] || !seen[0x002F] {
+		t.Errorf("Expected sequence numbers 0xFFF0 to 0x002F, got %v", seen)
+	}
+}
+
+func TestVerifySequence(t *testing.T) {
+	packager := &solarmanPackager{}
+	aduRequest := []byte{0xA5, 0x0E, 0x00, 0x10, 0x45, 0x01, 0x00, 0x4F, 0xAD, 0x6D, 0xA5}
+	aduResponse := []byte{0xA5, 0x0E, 0x00, 0x10, 0x15, 0x01, 0x07, 0x4F, 0xAD, 0x6D, 0xA5, 0x49, 0x15}
+
+	if err := packager.Verify(aduRequest, aduResponse); !errors.Is(err, ErrSequenceMismatch) {
+		t.Errorf("Expected sequence number mismatch, got %v", err)
+	}
+
+	packager.LooseSequence = true
+	if err := packager.Verify(aduRequest, aduResponse); err != nil {
+		t.Fatalf("Verify failed with LooseSequence: %v", err)
+	}
+
+	aduResponse[5], aduResponse[11] = 0x02, 0x4A
+	if err := packager.Verify(aduRequest, aduResponse); err == nil {
+		t.Errorf("Expected sequence number mismatch")
+	}
+}
@@ -0,0 +1,77 @@
+package gosolarman
+
+import "context"
+
+const (
+	// SpanRequest is the name of the span around a request of the ContextClient,
+	// from encoding the request to decoding the response.
+	SpanRequest = "solarman.request"
+
+	// SpanSend is the name of the span around the exchange of the transporter,
+	// including all retries.
+	SpanSend = "solarman.send"
+
+	// SpanPipeline is the name of the span around writing pipelined requests
+	// and reading their responses.
+	SpanPipeline = "solarman.pipeline"
+
+	// SpanDial is the name of the span around establishing a connection.
+	SpanDial = "solarman.dial"
+
+	// SpanWrite is the name of the span around writing a request frame.
+	SpanWrite = "solarman.write"
+
+	// SpanRead is the name of the span around reading the response frame.
+	SpanRead = "solarman.read"
+
+	// SpanVerify is the name of the span around verifying the response frame
+	// of an attempt of the transporter.
+	SpanVerify = "solarman.verify"
+
+	// SpanDecode is the name of the span around decoding the response frame
+	// of an attempt of the transporter.
+	SpanDecode = "solarman.decode"
+)
+
+// Tracer creates spans for the phases of an exchange.
+// Implementations must be safe for concurrent use.
+type Tracer interface {
+	// StartSpan starts a span with the given name (e.g., SpanSend) as a child
+	// of the span in ctx and returns a context that carries the new span.
+	StartSpan(ctx context.Context, name string) (context.Context, Span)
+}
+
+// Span is a span started by a Tracer.
+type Span interface {
+	// SetRequest records the attributes of the request frame of the exchange,
+	// such as the function code, register range and sequence number.
+	SetRequest(frame []byte)
+
+	// SetAttempts records the number of attempts made by the exchange.
+	SetAttempts(attempts int)
+
+	// End ends the span and records err if it is not nil.
+	End(err error)
+}
+
+// noopSpan is the Span used when no Tracer is configured.
+type noopSpan struct{}
+
+func (noopSpan) SetRequest(frame []byte)  {}
+func (noopSpan) SetAttempts(attempts int) {}
+func (noopSpan) End(err error)            {}
+
+// startSpan starts a span with the Tracer of the transporter, if configured.
+//
+// Parameters:
+//   - ctx: The context carrying the parent span.
+//   - name: The name of the span.
+//
+// Returns:
+//   - A context carrying the new span and the span itself.
+func (mb *solarmanTransporter) startSpan(ctx context.Context, name string) (context.Context, Span) {
+	if mb.Tracer == nil {
+		return ctx, noopSpan{}
+	}
+	return mb.Tracer.StartSpan(ctx, name)
+}
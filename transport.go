@@ -0,0 +1,95 @@
+package gosolarman
+
+import (
+	"context"
+	"net"
+
+	"github.com/grid-x/modbus"
+)
+
+// Dialer establishes connections to a data logging stick, e.g. through a
+// SOCKS proxy (golang.org/x/net/proxy) or an SSH tunnel (golang.org/x/crypto/ssh).
+type Dialer interface {
+	Dial(network, address string) (net.Conn, error)
+}
+
+// ContextDialer is a Dialer that can be cancelled through a context.
+// The transporter prefers DialContext over Dial when a Dialer implements it.
+type ContextDialer interface {
+	DialContext(ctx context.Context, network, address string) (net.Conn, error)
+}
+
+// DialerFunc adapts a function to the Dialer and ContextDialer interfaces,
+// e.g. to connect to an in-process net.Pipe in tests.
+type DialerFunc func(ctx context.Context, network, address string) (net.Conn, error)
+
+// Dial implements the Dialer interface.
+func (f DialerFunc) Dial(network, address string) (net.Conn, error) {
+	return f(context.Background(), network, address)
+}
+
+// DialContext implements the ContextDialer interface.
+func (f DialerFunc) DialContext(ctx context.Context, network, address string) (net.Conn, error) {
+	return f(ctx, network, address)
+}
+
+// Transport exchanges Solarman frames with a data logging stick. The
+// transporter of SolarmanClientHandler is the default implementation on top
+// of a Dialer; NewTransportClient puts the Solarman packager on any other.
+type Transport interface {
+	modbus.Transporter
+	modbus.Connector
+
+	// SendContext sends a request frame and returns the matching response frame.
+	SendContext(ctx context.Context, aduRequest []byte) (aduResponse []byte, err error)
+
+	// ConnectContext establishes the connection ahead of the first request.
+	ConnectContext(ctx context.Context) error
+}
+
+var _ Transport = (*solarmanTransporter)(nil)
+
+// transportClientHandler combines the Solarman packager with a Transport.
+type transportClientHandler struct {
+	modbus.Packager
+	Transport
+}
+
+// NewTransportClient creates a new Modbus client for Solarman devices that
+// sends its frames through a custom Transport.
+//
+// Parameters:
+//   - transport: The transport that exchanges the frames.
+//   - LoggerSerial: The serial number of the data logging stick.
+//   - SlaveID: The Modbus slave ID.
+//
+// Returns:
+//   - A Modbus client for interacting with the Solarman device.
+func NewTransportClient(transport Transport, LoggerSerial uint32, SlaveID byte) modbus.Client {
+	packager := NewSolarmanPackager(LoggerSerial)
+	packager.SetSlave(SlaveID)
+	return modbus.NewClient(&transportClientHandler{
+		Packager:  packager,
+		Transport: transport,
+	})
+}
+
+// dial connects to Address on Network with the configured Dialer, or with a
+// net.Dialer limited by Timeout if none is set.
+//
+// Parameters:
+//   - ctx: The context controlling the dial.
+//
+// Returns:
+//   - The established connection.
+//   - An error if the dial fails.
+func (mb *solarmanTransporter) dial(ctx context.Context) (net.Conn, error) {
+	dialer := mb.Dialer
+	if dialer == nil {
+		dialer = &net.Dialer{Timeout: mb.Timeout}
+	}
+	if d, ok := dialer.(ContextDialer); ok {
+		return d.DialContext(ctx, mb.network(), mb.Address)
+	}
+	return dialer.Dial(mb.network(), mb.Address)
+}
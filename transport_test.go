@@ -0,0 +1,97 @@
+package gosolarman
+
+import (
+	"context"
+	"errors"
+	"net"
+	"testing"
+)
+
+// plainDialer implements Dialer without DialContext.
+type plainDialer struct {
+	conn    net.Conn
+	network string
+	address string
+}
+
+func (d *plainDialer) Dial(network, address string) (net.Conn, error) {
+	d.network, d.address = network, address
+	return d.conn, nil
+}
+
+func TestDialerFunc(t *testing.T) {
+	client, server := net.Pipe()
+	defer server.Close()
+	go respond(t, server, 0x002A)
+
+	type key struct{}
+	var dialed context.Context
+	handler := NewSolarmanClientHandler("pipe", 0x12345678)
+	handler.SlaveID = 0x01
+	handler.Dialer = DialerFunc(func(ctx context.Context, network, address string) (net.Conn, error) {
+		if network != "tcp" || address != "pipe" {
+			t.Errorf("Expected tcp pipe, got %s %s", network, address)
+		}
+		dialed = ctx
+		return client, nil
+	})
+
+	ctx := context.WithValue(context.Background(), key{}, true)
+	results, err := NewContextClient(handler).ReadHoldingRegistersContext(ctx, 0, 1)
+	if err != nil {
+		t.Fatalf("ReadHoldingRegistersContext failed: %v", err)
+	}
+	if len(results) != 2 || results[1] != 0x2A {
+		t.Errorf("Expected results 002A, got %X", results)
+	}
+	if dialed == nil || dialed.Value(key{}) == nil {
+		t.Errorf("Expected DialContext to receive the request context")
+	}
+}
+
+func TestDialerWithoutContext(t *testing.T) {
+	client, server := net.Pipe()
+	defer server.Close()
+	go respond(t, server, 0x002A)
+
+	dialer := &plainDialer{conn: client}
+	handler := NewSolarmanClientHandler("192.168.1.1:8899", 0x12345678)
+	handler.SlaveID = 0x01
+	handler.Dialer = dialer
+
+	if _, err := NewContextClient(handler).ReadHoldingRegistersContext(context.Background(), 0, 1); err != nil {
+		t.Fatalf("ReadHoldingRegistersContext failed: %v", err)
+	}
+	if dialer.network != "tcp" || dialer.address != "192.168.1.1:8899" {
+		t.Errorf("Expected tcp 192.168.1.1:8899, got %s %s", dialer.network, dialer.address)
+	}
+}
+
+func TestDialerError(t *testing.T) {
+	errDial := errors.New("tunnel down")
+	handler := NewSolarmanClientHandler("192.168.1.1:8899", 0x12345678)
+	handler.Dialer = DialerFunc(func(ctx context.Context, network, address string) (net.Conn, error) {
+		return nil, errDial
+	})
+
+	if err := handler.Connect(); !errors.Is(err, errDial) {
+		t.Errorf("Expected dial error, got %v", err)
+	}
+}
+
+func TestTransportClient(t *testing.T) {
+	client, server := net.Pipe()
+	defer server.Close()
+	go respond(t, server, 0x0271)
+
+	transport := NewSolarmanClientHandler("pipe", 0x12345678)
+	transport.conn = client
+
+	results, err := NewTransportClient(&transport.solarmanTransporter, 0x12345678, 0x01).ReadHoldingRegisters(625, 1)
+	if err != nil {
+		t.Fatalf("ReadHoldingRegisters failed: %v", err)
+	}
+	if len(results) != 2 || results[0] != 0x02 || results[1] != 0x71 {
+		t.Errorf("Expected results 0271, got %X", results)
+	}
+}
@@ -3,11 +3,20 @@ package gosolarman
 import (
     "bytes"
     "encoding/binary"
+    "errors"
     "fmt"
 
     "github.com/grid-x/modbus"
 )
 
+const (
+    // requestPayloadLength is the length of the fixed fields of a request payload.
+    requestPayloadLength = 15
+
+    // responsePayloadLength is the length of the fixed fields of a response payload.
+    responsePayloadLength = 14
+)
+
 // Header represents the header of a Solarman frame.
 type Header struct {
     Start              byte   // Start byte of the frame, always 0xA5.
@@ -17,21 +26,92 @@ type Header struct {
     LoggerSerialNumber uint32 // Serial number of the data logging stick.
 }
 
+// Payload is the payload of a Solarman frame. The concrete type depends on the
+// control code of the frame.
+type Payload interface {
+    MarshalBinary() (data []byte, err error)
+    UnmarshalBinary(data []byte) error
+}
+
+// RequestPayload represents the payload of a Solarman request frame.
+type RequestPayload struct {
+    FrameType        byte                    // Frame type (e.g., 0x02 for solar inverter).
+    SensorType       uint16                  // Sensor type (e.g., 0x0000).
+    TotalWorkingTime uint32                  // Total working time of the data logging stick in seconds.
+    PowerOnTime      uint32                  // Current uptime of the data logging stick in seconds.
+    OffsetTime       uint32                  // Offset timestamp in seconds.
+    SlaveID          byte                    // Modbus slave ID of the RTU frame.
+    ModbusRTUFrame   modbus.ProtocolDataUnit // Modbus RTU request frame.
+}
+
 // ResponsePayload represents the payload of a Solarman response frame.
 type ResponsePayload struct {
-    FrameType        byte                  // Frame type (e.g., 0x02 for solar inverter).
-    Status           byte                  // Status of the request (e.g., 0x01 for real-time data).
-    TotalWorkingTime uint32                // Total working time of the data logging stick in seconds.
-    PowerOnTime      uint32                // Current uptime of the data logging stick in seconds.
-    OffsetTime       uint32                // Offset timestamp in seconds.
+    FrameType        byte                    // Frame type (e.g., 0x02 for solar inverter).
+    Status           byte                    // Status of the request (e.g., 0x01 for real-time data).
+    TotalWorkingTime uint32                  // Total working time of the data logging stick in seconds.
+    PowerOnTime      uint32                  // Current uptime of the data logging stick in seconds.
+    OffsetTime       uint32                  // Offset timestamp in seconds.
+    SlaveID          byte                    // Modbus slave ID of the RTU frame.
     ModbusRTUFrame   modbus.ProtocolDataUnit // Modbus RTU response frame.
 }
 
+// UnreachablePayload represents the payload of a response frame without a
+// Modbus RTU frame, sent by the data logging stick when the inverter does not respond.
+type UnreachablePayload struct {
+    FrameType        byte   // Frame type (e.g., 0x02 for solar inverter).
+    Status           byte   // Status of the request.
+    TotalWorkingTime uint32 // Total working time of the data logging stick in seconds.
+    PowerOnTime      uint32 // Current uptime of the data logging stick in seconds.
+    OffsetTime       uint32 // Offset timestamp in seconds.
+    Code             []byte // Bytes following the fixed fields instead of a Modbus RTU frame, if any.
+}
+
+// LoggerPayload represents the payload of a handshake, data, info or report
+// frame sent by the data logging stick on its own. The fixed fields are those
+// of a request payload; the layout of the data depends on the control code
+// and the firmware of the data logging stick.
+type LoggerPayload struct {
+    FrameType        byte   // Frame type (e.g., 0x01 for inverter data).
+    SensorType       uint16 // Sensor type.
+    TotalWorkingTime uint32 // Total working time of the data logging stick in seconds.
+    PowerOnTime      uint32 // Current uptime of the data logging stick in seconds.
+    OffsetTime       uint32 // Offset timestamp in seconds.
+    Data             []byte // Data following the fixed fields.
+}
+
+// HeartbeatPayload represents the payload of a heartbeat frame sent by the
+// data logging stick.
+type HeartbeatPayload struct {
+    Data []byte // Content of the heartbeat, usually a single 0x00 byte.
+}
+
+// RawPayload is the payload of a frame without a dedicated payload type, such
+// as the responses of the Solarman cloud to the frames of the data logging stick.
+type RawPayload []byte
+
+// Request represents a complete Solarman request, including the header, payload, and checksum.
+type Request struct {
+    Header   *Header         // Header of the request frame.
+    Payload  *RequestPayload // Payload of the request frame.
+    Checksum byte            // Checksum for verifying the integrity of the frame.
+}
+
 // Response represents a complete Solarman response, including the header, payload, and checksum.
 type Response struct {
-    Header   *Header         // Header of the response frame.
+    Header   *Header          // Header of the response frame.
     Payload  *ResponsePayload // Payload of the response frame.
-    Checksum byte            // Checksum for verifying the integrity of the frame.
+    Checksum byte             // Checksum for verifying the integrity of the frame.
+}
+
+// Frame represents any Solarman frame. Its Payload is a *RequestPayload for
+// ControlCodeRequest, a *ResponsePayload (or an *UnreachablePayload without a
+// Modbus RTU frame) for ControlCodeResponse, a *HeartbeatPayload for
+// ControlCodeHeartbeat, a *LoggerPayload for the other frames sent by the data
+// logging stick and a *RawPayload for all other control codes.
+type Frame struct {
+    Header   *Header // Header of the frame.
+    Payload  Payload // Payload of the frame.
+    Checksum byte    // Checksum for verifying the integrity of the frame.
 }
 
 // Parse parses a byte array into a Response structure.
@@ -42,58 +122,356 @@ type Response struct {
 //
 // Returns:
 //   - A pointer to the parsed Response structure.
-//   - An error if the parsing fails (e.g., invalid length, checksum mismatch).
+//   - An error if the parsing fails (e.g., invalid length, checksum mismatch), or an
+//     InverterError if the data logging stick reports that the inverter does not respond.
 func Parse(data []byte) (*Response, error) {
-    // Ensure the data is at least 11 bytes long
-    if len(data) < 11 {
-        return nil, fmt.Errorf("data too short, expected at least 11 bytes, got %d", len(data))
+    header, payloadBytes, err := parseFrame(data)
+    if err != nil {
+        return nil, err
     }
 
-    header, err := ParseHeader(data[:11])
-    if err != nil {
-        return nil, fmt.Errorf("failed to parse header: %w", err)
+    payload := &ResponsePayload{}
+    if err := payload.UnmarshalBinary(payloadBytes); err != nil {
+        var inverterErr *InverterError
+        if errors.As(err, &inverterErr) {
+            inverterErr.Frame = data
+        }
+        return nil, err
     }
 
-    // Calculate the checksum of all bytes except the last one
-    calculatedChecksum := CheckSum(data[1 : len(data)-2])
+    return &Response{
+        Header:   header,
+        Payload:  payload,
+        Checksum: data[len(data)-2],
+    }, nil
+}
 
-    // Compare the calculated checksum with the provided checksum (last byte)
-    providedChecksum := data[len(data)-2]
-    if calculatedChecksum != providedChecksum {
-        return nil, fmt.Errorf("checksum mismatch: calculated 0x%02X, provided 0x%02X", calculatedChecksum, providedChecksum)
+// ParseRequest parses a byte array into a Request structure.
+// It validates the header, payload, and checksum of the frame.
+//
+// Parameters:
+//   - data: The byte array representing the Solarman request frame.
+//
+// Returns:
+//   - A pointer to the parsed Request structure.
+//   - An error if the parsing fails (e.g., invalid length, checksum mismatch).
+func ParseRequest(data []byte) (*Request, error) {
+    header, payloadBytes, err := parseFrame(data)
+    if err != nil {
+        return nil, err
     }
 
-    responseLength := uint16(len(data[11 : len(data)-2]))
-    if header.Length != responseLength {
-        return nil, fmt.Errorf("invalid Length, expected %d, got %d", header.Length, responseLength)
+    payload := &RequestPayload{}
+    if err := payload.UnmarshalBinary(payloadBytes); err != nil {
+        return nil, err
     }
 
-    rtu := data[25 : len(data)-2]
-    if len(rtu) < 5 {
-        return nil, fmt.Errorf("invalid RTU frame, expected at least 5 bytes, got %d", len(rtu))
-    }
-    providedCrc := rtu[len(rtu)-2:]
-    expectedCrc := CRCFromBytes(rtu[:len(rtu)-2])
-    if !bytes.Equal(providedCrc, expectedCrc) {
-        return nil, fmt.Errorf("CRC mismatch: expected %v, got %v", expectedCrc, providedCrc)
+    return &Request{
+        Header:   header,
+        Payload:  payload,
+        Checksum: data[len(data)-2],
+    }, nil
+}
+
+// ParseFrame parses a byte array into a Frame structure, choosing the payload
+// type by the control code of the header. Unlike Parse, it returns responses
+// that report an unreachable inverter as a Frame with an *UnreachablePayload.
+//
+// Parameters:
+//   - data: The byte array representing the Solarman frame.
+//
+// Returns:
+//   - A pointer to the parsed Frame structure.
+//   - An error if the parsing fails (e.g., invalid length, checksum mismatch).
+func ParseFrame(data []byte) (*Frame, error) {
+    header, payloadBytes, err := parseFrame(data)
+    if err != nil {
+        return nil, err
     }
 
-    payload := &ResponsePayload{
-        FrameType:        data[11],
-        Status:           data[12],
-        TotalWorkingTime: binary.LittleEndian.Uint32(data[13:17]),
-        PowerOnTime:      binary.LittleEndian.Uint32(data[17:21]),
-        OffsetTime:       binary.LittleEndian.Uint32(data[21:25]),
-        ModbusRTUFrame:   parseRTUFrame(data[25 : len(data)-2]),
+    var payload Payload
+    switch header.ControlCode {
+    case ControlCodeRequest:
+        payload = &RequestPayload{}
+    case ControlCodeResponse:
+        payload = &ResponsePayload{}
+        if len(payloadBytes) >= responsePayloadLength && len(payloadBytes) < responsePayloadLength+5 {
+            payload = &UnreachablePayload{}
+        }
+    case ControlCodeHeartbeat:
+        payload = &HeartbeatPayload{}
+    case ControlCodeHandshake, ControlCodeData, ControlCodeInfo, ControlCodeReport:
+        payload = &LoggerPayload{}
+    default:
+        payload = &RawPayload{}
+    }
+    if err := payload.UnmarshalBinary(payloadBytes); err != nil {
+        return nil, err
     }
 
-    return &Response{
+    return &Frame{
         Header:   header,
         Payload:  payload,
-        Checksum: data[len(data)-1],
+        Checksum: data[len(data)-2],
     }, nil
 }
 
+// Marshal encodes the request into a byte array. The Start, Length and
+// Checksum fields are calculated. A request without payload is encoded with
+// an empty payload.
+//
+// Returns:
+//   - The encoded request frame.
+//   - An error if the encoding fails.
+func (r *Request) Marshal() ([]byte, error) {
+    if r.Payload == nil {
+        return marshalFrame(r.Header, nil)
+    }
+    return marshalFrame(r.Header, r.Payload)
+}
+
+// Marshal encodes the response into a byte array. The Start, Length and
+// Checksum fields are calculated. A response without payload is encoded with
+// an empty payload.
+//
+// Returns:
+//   - The encoded response frame.
+//   - An error if the encoding fails.
+func (r *Response) Marshal() ([]byte, error) {
+    if r.Payload == nil {
+        return marshalFrame(r.Header, nil)
+    }
+    return marshalFrame(r.Header, r.Payload)
+}
+
+// Marshal encodes the frame into a byte array. The Start, Length and
+// Checksum fields are calculated.
+//
+// Returns:
+//   - The encoded frame.
+//   - An error if the encoding fails.
+func (f *Frame) Marshal() ([]byte, error) {
+    return marshalFrame(f.Header, f.Payload)
+}
+
+// MarshalBinary encodes the request payload into a byte array.
+//
+// Returns:
+//   - The encoded payload including the Modbus RTU frame and its CRC.
+//   - An error if the encoding fails.
+func (p *RequestPayload) MarshalBinary() ([]byte, error) {
+    payload := new(bytes.Buffer)
+    payload.WriteByte(p.FrameType)
+    payload.Write(uint16ToBytes(p.SensorType, binary.LittleEndian))
+    payload.Write(uint32ToBytes(p.TotalWorkingTime, binary.LittleEndian))
+    payload.Write(uint32ToBytes(p.PowerOnTime, binary.LittleEndian))
+    payload.Write(uint32ToBytes(p.OffsetTime, binary.LittleEndian))
+    payload.Write(marshalRTUFrame(p.SlaveID, &p.ModbusRTUFrame))
+    return payload.Bytes(), nil
+}
+
+// UnmarshalBinary decodes a request payload from a byte array.
+//
+// Parameters:
+//   - data: The payload of a request frame.
+//
+// Returns:
+//   - An error if the payload is too short or the CRC does not match.
+func (p *RequestPayload) UnmarshalBinary(data []byte) error {
+    if len(data) < requestPayloadLength {
+        return newFrameError(ErrShortFrame, requestPayloadLength, uint32(len(data)), data)
+    }
+    slaveID, pdu, err := parseRTUFrame(data[requestPayloadLength:])
+    if err != nil {
+        return err
+    }
+    *p = RequestPayload{
+        FrameType:        data[0],
+        SensorType:       binary.LittleEndian.Uint16(data[1:3]),
+        TotalWorkingTime: binary.LittleEndian.Uint32(data[3:7]),
+        PowerOnTime:      binary.LittleEndian.Uint32(data[7:11]),
+        OffsetTime:       binary.LittleEndian.Uint32(data[11:15]),
+        SlaveID:          slaveID,
+        ModbusRTUFrame:   pdu,
+    }
+    return nil
+}
+
+// MarshalBinary encodes the response payload into a byte array.
+//
+// Returns:
+//   - The encoded payload including the Modbus RTU frame and its CRC.
+//   - An error if the encoding fails.
+func (p *ResponsePayload) MarshalBinary() ([]byte, error) {
+    payload := new(bytes.Buffer)
+    payload.WriteByte(p.FrameType)
+    payload.WriteByte(p.Status)
+    payload.Write(uint32ToBytes(p.TotalWorkingTime, binary.LittleEndian))
+    payload.Write(uint32ToBytes(p.PowerOnTime, binary.LittleEndian))
+    payload.Write(uint32ToBytes(p.OffsetTime, binary.LittleEndian))
+    payload.Write(marshalRTUFrame(p.SlaveID, &p.ModbusRTUFrame))
+    return payload.Bytes(), nil
+}
+
+// UnmarshalBinary decodes a response payload from a byte array.
+//
+// Parameters:
+//   - data: The payload of a response frame.
+//
+// Returns:
+//   - An error if the payload is too short or the CRC does not match, or an
+//     InverterError if the payload carries no Modbus RTU frame.
+func (p *ResponsePayload) UnmarshalBinary(data []byte) error {
+    if len(data) < responsePayloadLength {
+        return newFrameError(ErrShortFrame, responsePayloadLength, uint32(len(data)), data)
+    }
+
+    rtu := data[responsePayloadLength:]
+    if len(rtu) < 5 {
+        // The logger answered, but without a Modbus RTU frame from the inverter.
+        return &InverterError{
+            Status:           data[1],
+            TotalWorkingTime: binary.LittleEndian.Uint32(data[2:6]),
+            PowerOnTime:      binary.LittleEndian.Uint32(data[6:10]),
+            OffsetTime:       binary.LittleEndian.Uint32(data[10:14]),
+            Code:             rtu,
+        }
+    }
+    slaveID, pdu, err := parseRTUFrame(rtu)
+    if err != nil {
+        return err
+    }
+
+    *p = ResponsePayload{
+        FrameType:        data[0],
+        Status:           data[1],
+        TotalWorkingTime: binary.LittleEndian.Uint32(data[2:6]),
+        PowerOnTime:      binary.LittleEndian.Uint32(data[6:10]),
+        OffsetTime:       binary.LittleEndian.Uint32(data[10:14]),
+        SlaveID:          slaveID,
+        ModbusRTUFrame:   pdu,
+    }
+    return nil
+}
+
+// MarshalBinary encodes the payload of a response without a Modbus RTU frame into a byte array.
+//
+// Returns:
+//   - The encoded payload.
+//   - An error if the encoding fails.
+func (p *UnreachablePayload) MarshalBinary() ([]byte, error) {
+    payload := new(bytes.Buffer)
+    payload.WriteByte(p.FrameType)
+    payload.WriteByte(p.Status)
+    payload.Write(uint32ToBytes(p.TotalWorkingTime, binary.LittleEndian))
+    payload.Write(uint32ToBytes(p.PowerOnTime, binary.LittleEndian))
+    payload.Write(uint32ToBytes(p.OffsetTime, binary.LittleEndian))
+    payload.Write(p.Code)
+    return payload.Bytes(), nil
+}
+
+// UnmarshalBinary decodes the payload of a response without a Modbus RTU frame from a byte array.
+//
+// Parameters:
+//   - data: The payload of a response frame.
+//
+// Returns:
+//   - An error if the payload is too short.
+func (p *UnreachablePayload) UnmarshalBinary(data []byte) error {
+    if len(data) < responsePayloadLength {
+        return newFrameError(ErrShortFrame, responsePayloadLength, uint32(len(data)), data)
+    }
+    *p = UnreachablePayload{
+        FrameType:        data[0],
+        Status:           data[1],
+        TotalWorkingTime: binary.LittleEndian.Uint32(data[2:6]),
+        PowerOnTime:      binary.LittleEndian.Uint32(data[6:10]),
+        OffsetTime:       binary.LittleEndian.Uint32(data[10:14]),
+        Code:             bytes.Clone(data[responsePayloadLength:]),
+    }
+    return nil
+}
+
+// MarshalBinary encodes the payload of a frame sent by the data logging stick into a byte array.
+//
+// Returns:
+//   - The encoded payload.
+//   - An error if the encoding fails.
+func (p *LoggerPayload) MarshalBinary() ([]byte, error) {
+    payload := new(bytes.Buffer)
+    payload.WriteByte(p.FrameType)
+    payload.Write(uint16ToBytes(p.SensorType, binary.LittleEndian))
+    payload.Write(uint32ToBytes(p.TotalWorkingTime, binary.LittleEndian))
+    payload.Write(uint32ToBytes(p.PowerOnTime, binary.LittleEndian))
+    payload.Write(uint32ToBytes(p.OffsetTime, binary.LittleEndian))
+    payload.Write(p.Data)
+    return payload.Bytes(), nil
+}
+
+// UnmarshalBinary decodes the payload of a frame sent by the data logging stick from a byte array.
+//
+// Parameters:
+//   - data: The payload of a handshake, data, info or report frame.
+//
+// Returns:
+//   - An error if the payload is too short.
+func (p *LoggerPayload) UnmarshalBinary(data []byte) error {
+    if len(data) < requestPayloadLength {
+        return newFrameError(ErrShortFrame, requestPayloadLength, uint32(len(data)), data)
+    }
+    *p = LoggerPayload{
+        FrameType:        data[0],
+        SensorType:       binary.LittleEndian.Uint16(data[1:3]),
+        TotalWorkingTime: binary.LittleEndian.Uint32(data[3:7]),
+        PowerOnTime:      binary.LittleEndian.Uint32(data[7:11]),
+        OffsetTime:       binary.LittleEndian.Uint32(data[11:15]),
+        Data:             bytes.Clone(data[requestPayloadLength:]),
+    }
+    return nil
+}
+
+// MarshalBinary encodes the heartbeat payload into a byte array.
+//
+// Returns:
+//   - The encoded payload.
+//   - An error if the encoding fails.
+func (p *HeartbeatPayload) MarshalBinary() ([]byte, error) {
+    return bytes.Clone(p.Data), nil
+}
+
+// UnmarshalBinary decodes a heartbeat payload from a byte array.
+//
+// Parameters:
+//   - data: The payload of a heartbeat frame.
+//
+// Returns:
+//   - Always nil.
+func (p *HeartbeatPayload) UnmarshalBinary(data []byte) error {
+    *p = HeartbeatPayload{Data: bytes.Clone(data)}
+    return nil
+}
+
+// MarshalBinary returns a copy of the raw payload.
+//
+// Returns:
+//   - The raw payload.
+//   - An error if the encoding fails.
+func (p *RawPayload) MarshalBinary() ([]byte, error) {
+    return bytes.Clone(*p), nil
+}
+
+// UnmarshalBinary stores a copy of the raw payload.
+//
+// Parameters:
+//   - data: The payload of a frame.
+//
+// Returns:
+//   - Always nil.
+func (p *RawPayload) UnmarshalBinary(data []byte) error {
+    *p = bytes.Clone(data)
+    return nil
+}
+
 // ParseHeader parses the header of a Solarman frame from a byte array.
 //
 // Parameters:
@@ -104,8 +482,8 @@ func Parse(data []byte) (*Response, error) {
 //   - An error if the parsing fails (e.g., invalid start byte, insufficient length).
 func ParseHeader(data []byte) (*Header, error) {
     // Ensure the data is at least 11 bytes long
-    if len(data) < 11 {
-        return nil, fmt.Errorf("data too short, expected at least 11 bytes, got %d", len(data))
+    if len(data) < headerLength {
+        return nil, newFrameError(ErrShortFrame, headerLength, uint32(len(data)), data)
     }
 
     reader := bytes.NewReader(data)
@@ -116,7 +494,7 @@ func ParseHeader(data []byte) (*Header, error) {
         return nil, fmt.Errorf("failed to read Start: %w", err)
     }
     if start != StartByte {
-        return nil, fmt.Errorf("invalid Start byte, expected 0x%02X, got 0x%02X", StartByte, start)
+        return nil, newFrameError(ErrBadStartByte, StartByte, uint32(start), data)
     }
 
     // Parse Length
@@ -153,16 +531,122 @@ func ParseHeader(data []byte) (*Header, error) {
     }, nil
 }
 
-// parseRTUFrame parses the Modbus RTU frame from the payload.
+// parseFrame validates the envelope of a Solarman frame.
+// It checks the header, the Length field, the End byte and the checksum.
+//
+// Parameters:
+//   - data: The byte array representing the Solarman frame.
+//
+// Returns:
+//   - header: The parsed header.
+//   - payload: The payload of the frame.
+//   - err: An error if the validation fails.
+func parseFrame(data []byte) (header *Header, payload []byte, err error) {
+    // Ensure the data is at least as long as header and trailer
+    if len(data) < headerLength+trailerLength {
+        return nil, nil, newFrameError(ErrShortFrame, headerLength+trailerLength, uint32(len(data)), data)
+    }
+
+    header, err = ParseHeader(data[:headerLength])
+    if err != nil {
+        return nil, nil, fmt.Errorf("failed to parse header: %w", err)
+    }
+
+    // Cross-check the Length field before slicing the payload
+    if frameLength := headerLength + int(header.Length) + trailerLength; len(data) != frameLength {
+        return nil, nil, newFrameError(ErrLengthMismatch, uint32(frameLength), uint32(len(data)), data)
+    }
+
+    // Verify the End byte
+    if end := data[len(data)-1]; end != EndByte {
+        return nil, nil, newFrameError(ErrBadEndByte, EndByte, uint32(end), data)
+    }
+
+    // Calculate the checksum of all bytes except Start, Checksum and End
+    calculatedChecksum := CheckSum(data[1 : len(data)-2])
+
+    // Compare the calculated checksum with the provided checksum (second to last byte)
+    providedChecksum := data[len(data)-2]
+    if calculatedChecksum != providedChecksum {
+        return nil, nil, newFrameError(ErrChecksum, uint32(calculatedChecksum), uint32(providedChecksum), data)
+    }
+
+    return header, data[headerLength : len(data)-trailerLength], nil
+}
+
+// marshalFrame encodes a header and payload into a Solarman frame.
+//
+// Parameters:
+//   - header: The header of the frame. Start and Length are ignored.
+//   - payload: The payload of the frame.
+//
+// Returns:
+//   - The encoded frame.
+//   - An error if the header is missing or the payload cannot be encoded.
+func marshalFrame(header *Header, payload Payload) ([]byte, error) {
+    if header == nil {
+        return nil, fmt.Errorf("missing header")
+    }
+    var payloadBytes []byte
+    if payload != nil {
+        var err error
+        if payloadBytes, err = payload.MarshalBinary(); err != nil {
+            return nil, err
+        }
+    }
+    if len(payloadBytes) > 0xFFFF {
+        return nil, fmt.Errorf("payload too long: %d bytes", len(payloadBytes))
+    }
+
+    frame := new(bytes.Buffer)
+    frame.WriteByte(StartByte)
+    frame.Write(uint16ToBytes(uint16(len(payloadBytes)), binary.LittleEndian))
+    frame.Write(uint16ToBytes(header.ControlCode, binary.LittleEndian))
+    frame.Write(uint16ToBytes(header.SequenceNumber, binary.LittleEndian))
+    frame.Write(uint32ToBytes(header.LoggerSerialNumber, binary.LittleEndian))
+    frame.Write(payloadBytes)
+
+    frame.WriteByte(CheckSum(frame.Bytes()[1:]))
+    frame.WriteByte(EndByte)
+
+    return frame.Bytes(), nil
+}
+
+// parseRTUFrame parses the Modbus RTU frame from the payload and verifies its CRC.
 //
 // Parameters:
 //   - data: The byte array representing the Modbus RTU frame.
 //
 // Returns:
-//   - A ProtocolDataUnit containing the function code and data.
-func parseRTUFrame(data []byte) modbus.ProtocolDataUnit {
-    return modbus.ProtocolDataUnit{
+//   - slaveID: The Modbus slave ID.
+//   - pdu: A ProtocolDataUnit containing the function code and data.
+//   - err: An error if the frame is too short or the CRC does not match.
+func parseRTUFrame(data []byte) (slaveID byte, pdu modbus.ProtocolDataUnit, err error) {
+    if len(data) < 4 {
+        return 0, pdu, newFrameError(ErrShortFrame, 4, uint32(len(data)), data)
+    }
+    providedCrc := data[len(data)-2:]
+    expectedCrc := CRCFromBytes(data[:len(data)-2])
+    if !bytes.Equal(providedCrc, expectedCrc) {
+        return 0, pdu, newFrameError(ErrCRC, uint32(binary.LittleEndian.Uint16(expectedCrc)), uint32(binary.LittleEndian.Uint16(providedCrc)), data)
+    }
+    return data[0], modbus.ProtocolDataUnit{
         FunctionCode: data[1],
         Data:         data[2 : len(data)-2],
-    }
-}
\ No newline at end of file
+    }, nil
+}
+
+// marshalRTUFrame encodes a Modbus RTU frame including its CRC.
+//
+// Parameters:
+//   - slaveID: The Modbus slave ID.
+//   - pdu: The Modbus Protocol Data Unit.
+//
+// Returns:
+//   - The encoded Modbus RTU frame.
+func marshalRTUFrame(slaveID byte, pdu *modbus.ProtocolDataUnit) []byte {
+    rtu := make([]byte, 0, len(pdu.Data)+4)
+    rtu = append(rtu, slaveID, pdu.FunctionCode)
+    rtu = append(rtu, pdu.Data...)
+    return append(rtu, CRC(slaveID, pdu)...)
+}
@@ -3,7 +3,11 @@ package gosolarman
 import (
 	"bytes"
 	"encoding/binary"
+	"encoding/hex"
+	"fmt"
 	"testing"
+
+	"github.com/grid-x/modbus"
 )
 
 func TestParseHeader(t *testing.T) {
@@ -86,6 +90,89 @@ func TestParse(t *testing.T) {
 	}
 }
 
+func TestMarshalRoundTrip(t *testing.T) {
+	frames := []struct {
+		frame   string
+		payload string
+	}{
+		{"a5170010452a00d302964902000000000000000000000000000001030271000295a80215", "*gosolarman.RequestPayload"},                      // Request
+		{"a517001015012ad302964902013412000058020000f3a01265010304000102716ab76515", "*gosolarman.ResponsePayload"},                     // Response
+		{"a513001015022bd302964902013412000058020000f3a01265018302c0f1fd15", "*gosolarman.ResponsePayload"},                             // Exception response
+		{"a5100010152c00d302964902013412000058020000f3a012650500c715", "*gosolarman.UnreachablePayload"},                                // Response without RTU frame
+		{"a50e0010152c00d302964902013412000058020000f3a01265c015", "*gosolarman.UnreachablePayload"},                                    // Empty response
+		{"a5010010470401d3029649001115", "*gosolarman.HeartbeatPayload"},                                                                // Heartbeat
+		{"a5220010410201d30296490200003412000058020000f3a012654c5357335f31355f464646465f312e302e3635c215", "*gosolarman.LoggerPayload"}, // Handshake
+		{"a5170010420302d30296490100003412000058020000f3a012650001020304050607e915", "*gosolarman.LoggerPayload"},                       // Data
+		{"a5130010430403d30296490300003412000058020000f3a01265c0a8010a4115", "*gosolarman.LoggerPayload"},                               // Info
+		{"a50f0010480504d30296490800003412000058020000f3a01265d615", "*gosolarman.LoggerPayload"},                                       // Report
+	}
+	for _, test := range frames {
+		data, _ := hex.DecodeString(test.frame)
+		parsed, err := ParseFrame(data)
+		if err != nil {
+			t.Fatalf("ParseFrame failed for %s: %v", test.frame, err)
+		}
+		if payload := fmt.Sprintf("%T", parsed.Payload); payload != test.payload {
+			t.Errorf("Expected payload %s for %s, got %s", test.payload, test.frame, payload)
+		}
+		marshalled, err := parsed.Marshal()
+		if err != nil {
+			t.Fatalf("Marshal failed for %s: %v", test.frame, err)
+		}
+		if !bytes.Equal(data, marshalled) {
+			t.Errorf("Expected %X, got %X", data, marshalled)
+		}
+	}
+}
+
+func TestRequestRoundTrip(t *testing.T) {
+	packager := &solarmanPackager{SlaveID: 0x01, LoggerSerial: 1234567891}
+	packager.SetSequence(0x002A)
+	adu, err := packager.Encode(&modbus.ProtocolDataUnit{FunctionCode: 0x03, Data: []byte{0x02, 0x71, 0x00, 0x02}})
+	if err != nil {
+		t.Fatalf("Encode failed: %v", err)
+	}
+
+	request, err := ParseRequest(adu)
+	if err != nil {
+		t.Fatalf("ParseRequest failed: %v", err)
+	}
+	if request.Header.SequenceNumber != 0x002A || request.Header.LoggerSerialNumber != 1234567891 {
+		t.Errorf("Unexpected header %+v", request.Header)
+	}
+	if request.Payload.SlaveID != 0x01 || request.Payload.ModbusRTUFrame.FunctionCode != 0x03 {
+		t.Errorf("Unexpected payload %+v", request.Payload)
+	}
+
+	marshalled, err := request.Marshal()
+	if err != nil {
+		t.Fatalf("Marshal failed: %v", err)
+	}
+	if !bytes.Equal(adu, marshalled) {
+		t.Errorf("Expected %X, got %X", adu, marshalled)
+	}
+}
+
+func TestMarshalWithoutPayload(t *testing.T) {
+	header := &Header{ControlCode: ControlCodeRequest, SequenceNumber: 0x0102, LoggerSerialNumber: 0x12345678}
+	request, err := (&Request{Header: header}).Marshal()
+	if err != nil {
+		t.Fatalf("Marshal failed for a request: %v", err)
+	}
+	if expected := []byte{0xA5, 0x00, 0x00, 0x10, 0x45, 0x02, 0x01, 0x78, 0x56, 0x34, 0x12, 0x6C, 0x15}; !bytes.Equal(request, expected) {
+		t.Errorf("Expected %X, got %X", expected, request)
+	}
+
+	header.ControlCode = ControlCodeResponse
+	response, err := (&Response{Header: header}).Marshal()
+	if err != nil {
+		t.Fatalf("Marshal failed for a response: %v", err)
+	}
+	if expected := []byte{0xA5, 0x00, 0x00, 0x10, 0x15, 0x02, 0x01, 0x78, 0x56, 0x34, 0x12, 0x3C, 0x15}; !bytes.Equal(response, expected) {
+		t.Errorf("Expected %X, got %X", expected, response)
+	}
+}
+
 func TestUint16ToBytes(t *testing.T) {
 	value := uint16(0x1234)
 	ret := uint16ToBytes(value, binary.LittleEndian)
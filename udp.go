@@ -0,0 +1,134 @@
+package gosolarman
+
+import (
+	"context"
+	"log/slog"
+	"strings"
+	"time"
+)
+
+const (
+	// NetworkTCP is the network of a transporter that keeps a TCP connection
+	// to the data logging stick.
+	NetworkTCP = "tcp"
+
+	// NetworkUDP is the network of a transporter that exchanges one frame per
+	// datagram with the data logging stick.
+	NetworkUDP = "udp"
+
+	// RetransmitInterval is the default interval after which an unanswered
+	// request is sent again over UDP.
+	RetransmitInterval = time.Second
+)
+
+// splitAddress splits an optional "tcp://" or "udp://" scheme off an address.
+//
+// Parameters:
+//   - address: The address, e.g. "udp://192.168.1.1:8899" or "192.168.1.1:8899".
+//
+// Returns:
+//   - network: The network of the scheme, NetworkTCP if there is none.
+//   - hostport: The address without the scheme.
+func splitAddress(address string) (network, hostport string) {
+	if scheme, rest, ok := strings.Cut(address, "://"); ok && (scheme == NetworkTCP || scheme == NetworkUDP) {
+		return scheme, rest
+	}
+	return NetworkTCP, address
+}
+
+// network returns the network of the transporter.
+//
+// Returns:
+//   - Network, or NetworkTCP if it is not set.
+func (mb *solarmanTransporter) network() string {
+	if mb.Network == "" {
+		return NetworkTCP
+	}
+	return mb.Network
+}
+
+// datagram reports whether the transporter exchanges frames as datagrams.
+//
+// Returns:
+//   - true if Network is NetworkUDP.
+func (mb *solarmanTransporter) datagram() bool {
+	return mb.Network == NetworkUDP
+}
+
+// retransmitDeadline returns the read deadline for one transmission of a
+// request over UDP, which is RetransmitInterval from now unless the deadline
+// of the exchange is earlier.
+//
+// Parameters:
+//   - deadline: The deadline of the exchange, or the zero time if there is none.
+//
+// Returns:
+//   - readDeadline: The read deadline for the transmission.
+//   - retransmit: Whether the request is sent again when readDeadline expires.
+func (mb *solarmanTransporter) retransmitDeadline(deadline time.Time) (readDeadline time.Time, retransmit bool) {
+	if !mb.datagram() || mb.RetransmitInterval <= 0 {
+		return deadline, false
+	}
+	next := time.Now().Add(mb.RetransmitInterval)
+	if !deadline.IsZero() && !next.Before(deadline) {
+		return deadline, false
+	}
+	return next, true
+}
+
+// readDatagram reads the next datagram that holds a complete frame, skipping
+// truncated and malformed datagrams.
+//
+// Parameters:
+//   - ctx: The context controlling the exchange.
+//
+// Returns:
+//   - frame: The raw frame, from StartByte to EndByte inclusive.
+//   - err: An error if the connection fails.
+func (mb *solarmanTransporter) readDatagram(ctx context.Context) (frame []byte, err error) {
+	buffer := make([]byte, headerLength+maxPayloadLength+trailerLength+1)
+	for {
+		n, err := mb.conn.Read(buffer)
+		if err != nil {
+			return nil, err
+		}
+		if isCompleteFrame(buffer[:n]) {
+			return append([]byte(nil), buffer[:n]...), nil
+		}
+		if mb.logEnabled(ctx, slog.LevelDebug) {
+			mb.log(ctx, slog.LevelDebug, "skipped malformed datagram", slog.Int("length", n))
+		}
+	}
+}
+
+// isCompleteFrame reports whether data holds exactly one frame whose Length
+// field matches its size.
+//
+// Parameters:
+//   - data: The data of a datagram.
+//
+// Returns:
+//   - true if data is a complete frame.
+func isCompleteFrame(data []byte) bool {
+	if len(data) < headerLength+trailerLength || len(data) > headerLength+maxPayloadLength+trailerLength {
+		return false
+	}
+	length := int(data[1]) | int(data[2])<<8
+	return data[0] == StartByte && data[3] == controlCodeSuffix &&
+		data[len(data)-1] == EndByte && len(data) == headerLength+length+trailerLength
+}
+
+// sameLoggerSerial reports whether frame carries the logger serial number of request.
+//
+// Parameters:
+//   - request: The request frame.
+//   - frame: The received frame.
+//
+// Returns:
+//   - true if the logger serial numbers are equal.
+func sameLoggerSerial(request []byte, frame []byte) bool {
+	if len(request) < headerLength || len(frame) < headerLength {
+		return true // Leave the rejection of short frames to Verify.
+	}
+	return string(request[7:11]) == string(frame[7:11])
+}
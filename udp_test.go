@@ -0,0 +1,122 @@
+package gosolarman
+
+import (
+	"context"
+	"errors"
+	"net"
+	"os"
+	"testing"
+	"time"
+)
+
+func TestSplitAddress(t *testing.T) {
+	tests := []struct {
+		address  string
+		network  string
+		hostport string
+	}{
+		{"192.168.1.1:8899", NetworkTCP, "192.168.1.1:8899"},
+		{"tcp://192.168.1.1:8899", NetworkTCP, "192.168.1.1:8899"},
+		{"udp://192.168.1.1:8899", NetworkUDP, "192.168.1.1:8899"},
+		{"unix:///tmp/logger", NetworkTCP, "unix:///tmp/logger"},
+	}
+	for _, test := range tests {
+		network, hostport := splitAddress(test.address)
+		if network != test.network || hostport != test.hostport {
+			t.Errorf("%s: expected %s %s, got %s %s", test.address, test.network, test.hostport, network, hostport)
+		}
+	}
+
+	handler := NewSolarmanClientHandler("udp://192.168.1.1:8899", 0x12345678)
+	if handler.Network != NetworkUDP || handler.Address != "192.168.1.1:8899" || handler.RetransmitInterval != RetransmitInterval {
+		t.Errorf("Expected UDP handler, got %s %s %v", handler.Network, handler.Address, handler.RetransmitInterval)
+	}
+}
+
+func TestIsCompleteFrame(t *testing.T) {
+	frame := []byte{0xA5, 0x00, 0x00, 0x10, 0x15, 0x01, 0x00, 0x4F, 0xAD, 0x6D, 0xA5, 0x00, 0x15}
+	if !isCompleteFrame(frame) {
+		t.Errorf("Expected %X to be complete", frame)
+	}
+	if isCompleteFrame(frame[:12]) {
+		t.Errorf("Expected truncated frame to be incomplete")
+	}
+	if isCompleteFrame(append(frame, 0x00)) {
+		t.Errorf("Expected frame with trailing data to be incomplete")
+	}
+}
+
+func TestUDPRetransmit(t *testing.T) {
+	server, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("ListenPacket failed: %v", err)
+	}
+	defer server.Close()
+
+	go func() {
+		buffer := make([]byte, 512)
+		for transmission := 1; ; transmission++ {
+			n, addr, err := server.ReadFrom(buffer)
+			if err != nil {
+				return
+			}
+			if transmission == 1 {
+				continue // Drop the first transmission.
+			}
+			request := buffer[:n]
+			stale := responseFrame(request, 0xFFFF)
+			stale[7]++
+			stale[len(stale)-2]++
+			server.WriteTo(stale, addr)
+			server.WriteTo([]byte{0xA5, 0x01, 0x02}, addr)
+			server.WriteTo(responseFrame(request, 0x002A), addr)
+		}
+	}()
+
+	handler := NewSolarmanClientHandler("udp://"+server.LocalAddr().String(), 0x12345678)
+	handler.SlaveID = 0x01
+	handler.RetransmitInterval = 50 * time.Millisecond
+	defer handler.Close()
+
+	results, err := NewContextClient(handler).ReadHoldingRegistersContext(context.Background(), 0, 1)
+	if err != nil {
+		t.Fatalf("ReadHoldingRegistersContext failed: %v", err)
+	}
+	if len(results) != 2 || results[1] != 0x2A {
+		t.Errorf("Expected results 002A, got %X", results)
+	}
+}
+
+func TestUDPTimeout(t *testing.T) {
+	server, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("ListenPacket failed: %v", err)
+	}
+	defer server.Close()
+
+	transmissions := make(chan struct{}, 16)
+	go func() {
+		buffer := make([]byte, 512)
+		for {
+			if _, _, err := server.ReadFrom(buffer); err != nil {
+				return
+			}
+			transmissions <- struct{}{}
+		}
+	}()
+
+	handler := NewSolarmanClientHandler("udp://"+server.LocalAddr().String(), 0x12345678)
+	handler.Timeout = 250 * time.Millisecond
+	handler.RetransmitInterval = 100 * time.Millisecond
+	handler.RetryPolicy = &BackoffRetryPolicy{MaxAttempts: 1}
+	defer handler.Close()
+
+	_, err = handler.Send([]byte{0xA5, 0x0E, 0x00, 0x10, 0x45, 0x01, 0x00, 0x78, 0x56, 0x34, 0x12, 0x00, 0x15})
+	if !errors.Is(err, os.ErrDeadlineExceeded) {
+		t.Fatalf("Expected deadline exceeded, got %v", err)
+	}
+	time.Sleep(20 * time.Millisecond)
+	if n := len(transmissions); n != 3 {
+		t.Errorf("Expected 3 transmissions, got %d", n)
+	}
+}